@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// Usage is the evcc meter usage
+type Usage string
+
+const (
+	UsageGrid    Usage = "grid"
+	UsagePV      Usage = "pv"
+	UsageCharge  Usage = "charge"
+	UsageBattery Usage = "battery"
+	UsageAux     Usage = "aux"
+)
+
+// ParseUsage validates and converts a string to a Usage
+func ParseUsage(s string) (Usage, error) {
+	switch u := Usage(s); u {
+	case UsageGrid, UsagePV, UsageCharge, UsageBattery, UsageAux:
+		return u, nil
+	default:
+		return "", fmt.Errorf("invalid usage: %s", s)
+	}
+}
+
+// sign returns the factor converting HomeWizard power to evcc power for this usage
+// HomeWizard reports positive = consumption (import, charging) for all devices,
+// evcc expects positive = production for pv and positive = discharging for battery
+func (u Usage) sign() float64 {
+	switch u {
+	case UsagePV, UsageBattery:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// New returns an evcc meter for the device and usage
+// Depending on device and usage the result also implements api.MeterEnergy, api.PhasePowers,
+// api.PhaseCurrents, api.PhaseVoltages, api.Battery and api.BatteryCapacity
+// Phases (1 or 3) determines how per-phase values are reported for grid and kWh meters
+func New(dev any, usage Usage, phases int) (api.Meter, error) {
+	if _, err := ParseUsage(string(usage)); err != nil {
+		return nil, err
+	}
+
+	if phases != 1 && phases != 3 {
+		return nil, fmt.Errorf("invalid phases: %d", phases)
+	}
+
+	switch d := dev.(type) {
+	case *device.P1MeterDevice:
+		return newP1Meter(d, usage, phases)
+	case *device.KWHMeterDevice:
+		return newKWHMeter(d, usage, phases), nil
+	case *device.EnergySocketDevice:
+		return newSocketMeter(d, usage)
+	case *device.BatteryDevice:
+		return newBatteryMeter(d, usage)
+	case *device.BatteryGroup:
+		return newBatteryGroupMeter(d, usage)
+	default:
+		return nil, fmt.Errorf("unsupported device: %T", dev)
+	}
+}
@@ -0,0 +1,103 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// battery adapts a HomeWizard battery or battery group to the evcc battery meter interfaces
+type battery struct {
+	usage    Usage
+	measure  func() (device.BatteryMeasurement, error)
+	capacity func() float64
+}
+
+var (
+	_ api.Meter           = (*battery)(nil)
+	_ api.MeterEnergy     = (*battery)(nil)
+	_ api.PhaseCurrents   = (*battery)(nil)
+	_ api.PhaseVoltages   = (*battery)(nil)
+	_ api.Battery         = (*battery)(nil)
+	_ api.BatteryCapacity = (*battery)(nil)
+)
+
+// newBatteryMeter creates a battery meter from a battery device
+func newBatteryMeter(d *device.BatteryDevice, usage Usage) (*battery, error) {
+	if usage != UsageBattery {
+		return nil, fmt.Errorf("invalid usage for %s: %s", d.Type(), usage)
+	}
+
+	return &battery{
+		usage:    usage,
+		measure:  d.GetMeasurement,
+		capacity: d.Capacity,
+	}, nil
+}
+
+// newBatteryGroupMeter creates a battery meter from a battery group
+func newBatteryGroupMeter(g *device.BatteryGroup, usage Usage) (*battery, error) {
+	if usage != UsageBattery {
+		return nil, fmt.Errorf("invalid usage for battery group: %s", usage)
+	}
+
+	return &battery{
+		usage: usage,
+		measure: func() (device.BatteryMeasurement, error) {
+			m, err := g.GetMeasurement()
+			return m.BatteryMeasurement, err
+		},
+		capacity: g.Capacity,
+	}, nil
+}
+
+// CurrentPower implements the api.Meter interface
+func (b *battery) CurrentPower() (float64, error) {
+	m, err := b.measure()
+	if err != nil {
+		return 0, err
+	}
+	return b.usage.sign() * m.PowerW, nil
+}
+
+// TotalEnergy implements the api.MeterEnergy interface
+func (b *battery) TotalEnergy() (float64, error) {
+	m, err := b.measure()
+	if err != nil {
+		return 0, err
+	}
+	return m.EnergyImportkWh, nil
+}
+
+// Currents implements the api.PhaseCurrents interface
+func (b *battery) Currents() (float64, float64, float64, error) {
+	m, err := b.measure()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return b.usage.sign() * m.CurrentA, 0, 0, nil
+}
+
+// Voltages implements the api.PhaseVoltages interface
+func (b *battery) Voltages() (float64, float64, float64, error) {
+	m, err := b.measure()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return m.VoltageV, 0, 0, nil
+}
+
+// Soc implements the api.Battery interface
+func (b *battery) Soc() (float64, error) {
+	m, err := b.measure()
+	if err != nil {
+		return 0, err
+	}
+	return m.StateOfChargePct, nil
+}
+
+// Capacity implements the api.BatteryCapacity interface
+func (b *battery) Capacity() float64 {
+	return b.capacity()
+}
@@ -0,0 +1,96 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// DefaultStandbyPower is the power draw in W up to which a switched on socket is considered not charging
+const DefaultStandbyPower = 10.0
+
+// charger adapts a HomeWizard energy socket to a switch socket charger
+// Charging is inferred from the power draw, the charging current can't be controlled
+type charger struct {
+	socket       *device.EnergySocketDevice
+	standbyPower float64
+}
+
+var (
+	_ api.Charger        = (*charger)(nil)
+	_ api.ChargerEx      = (*charger)(nil)
+	_ api.Meter          = (*charger)(nil)
+	_ api.MeterEnergy    = (*charger)(nil)
+	_ api.PhaseDescriber = (*charger)(nil)
+)
+
+// NewCharger returns an evcc charger for an energy socket
+// The vehicle is considered charging while the socket draws more than standbyPower (W)
+func NewCharger(d *device.EnergySocketDevice, standbyPower float64) (api.Charger, error) {
+	if standbyPower < 0 {
+		return nil, fmt.Errorf("invalid standby power: %.0f", standbyPower)
+	}
+
+	return &charger{
+		socket:       d,
+		standbyPower: standbyPower,
+	}, nil
+}
+
+// Status implements the api.ChargeState interface
+func (c *charger) Status() (api.ChargeStatus, error) {
+	power, err := c.socket.GetPower(false)
+	if err != nil {
+		return api.StatusNone, err
+	}
+
+	if power > c.standbyPower {
+		return api.StatusC, nil
+	}
+	return api.StatusB, nil
+}
+
+// Enabled implements the api.Charger interface
+func (c *charger) Enabled() (bool, error) {
+	return c.socket.IsPowerOn()
+}
+
+// Enable implements the api.Charger interface
+func (c *charger) Enable(enable bool) error {
+	return c.socket.SetPowerOn(enable)
+}
+
+// MaxCurrent implements the api.Charger interface
+func (c *charger) MaxCurrent(current int64) error {
+	return nil
+}
+
+// MaxCurrentMillis implements the api.ChargerEx interface
+func (c *charger) MaxCurrentMillis(current float64) error {
+	return nil
+}
+
+// CurrentPower implements the api.Meter interface
+// Standby consumption is reported as zero
+func (c *charger) CurrentPower() (float64, error) {
+	power, err := c.socket.GetPower(false)
+	if err != nil {
+		return 0, err
+	}
+
+	if power <= c.standbyPower {
+		return 0, nil
+	}
+	return power, nil
+}
+
+// TotalEnergy implements the api.MeterEnergy interface
+func (c *charger) TotalEnergy() (float64, error) {
+	return c.socket.GetTotalEnergy()
+}
+
+// Phases implements the api.PhaseDescriber interface
+func (c *charger) Phases() int {
+	return 1
+}
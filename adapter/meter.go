@@ -0,0 +1,136 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// meter adapts a HomeWizard grid or kWh meter to the evcc meter interfaces
+type meter struct {
+	usage   Usage
+	phases  int
+	measure func() (device.CommonMeasurement, error)
+	energy  func() (float64, error)
+}
+
+var (
+	_ api.Meter         = (*meter)(nil)
+	_ api.MeterEnergy   = (*meter)(nil)
+	_ api.PhasePowers   = (*meter)(nil)
+	_ api.PhaseCurrents = (*meter)(nil)
+	_ api.PhaseVoltages = (*meter)(nil)
+)
+
+// newP1Meter creates a grid meter from a P1 meter
+func newP1Meter(d *device.P1MeterDevice, usage Usage, phases int) (*meter, error) {
+	if usage != UsageGrid {
+		return nil, fmt.Errorf("invalid usage for %s: %s", d.Type(), usage)
+	}
+
+	return &meter{
+		usage:  usage,
+		phases: phases,
+		measure: func() (device.CommonMeasurement, error) {
+			m, err := d.GetMeasurement()
+			return m.CommonMeasurement, err
+		},
+		energy: d.GetImportEnergy,
+	}, nil
+}
+
+// newKWHMeter creates a meter of any usage from a kWh meter
+func newKWHMeter(d *device.KWHMeterDevice, usage Usage, phases int) *meter {
+	return &meter{
+		usage:  usage,
+		phases: phases,
+		measure: func() (device.CommonMeasurement, error) {
+			m, err := d.GetMeasurement()
+			return m.CommonMeasurement, err
+		},
+		energy: func() (float64, error) {
+			// PV production is counted as export
+			return d.GetTotalEnergy(usage == UsagePV)
+		},
+	}
+}
+
+// newSocketMeter creates a single phase meter from an energy socket
+func newSocketMeter(d *device.EnergySocketDevice, usage Usage) (*meter, error) {
+	if usage == UsageGrid || usage == UsageBattery {
+		return nil, fmt.Errorf("invalid usage for %s: %s", d.Type(), usage)
+	}
+
+	return &meter{
+		usage:  usage,
+		phases: 1,
+		measure: func() (device.CommonMeasurement, error) {
+			m, err := d.GetMeasurement()
+			return m.CommonMeasurement, err
+		},
+		energy: func() (float64, error) {
+			m, err := d.GetMeasurement()
+			if usage == UsagePV {
+				// PV production is counted as export
+				return m.EnergyExportkWh, err
+			}
+			return m.EnergyImportkWh, err
+		},
+	}, nil
+}
+
+// CurrentPower implements the api.Meter interface
+func (m *meter) CurrentPower() (float64, error) {
+	c, err := m.measure()
+	if err != nil {
+		return 0, err
+	}
+	return m.usage.sign() * c.PowerW, nil
+}
+
+// TotalEnergy implements the api.MeterEnergy interface
+func (m *meter) TotalEnergy() (float64, error) {
+	return m.energy()
+}
+
+// Powers implements the api.PhasePowers interface
+func (m *meter) Powers() (float64, float64, float64, error) {
+	c, err := m.measure()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	sign := m.usage.sign()
+	if m.phases == 1 {
+		return sign * c.PowerW, 0, 0, nil
+	}
+	return sign * c.PowerL1W, sign * c.PowerL2W, sign * c.PowerL3W, nil
+}
+
+// Currents implements the api.PhaseCurrents interface
+func (m *meter) Currents() (float64, float64, float64, error) {
+	c, err := m.measure()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	sign := m.usage.sign()
+	if m.phases == 1 {
+		return sign * c.CurrentA, 0, 0, nil
+	}
+	return sign * c.CurrentL1A, sign * c.CurrentL2A, sign * c.CurrentL3A, nil
+}
+
+// Voltages implements the api.PhaseVoltages interface
+func (m *meter) Voltages() (float64, float64, float64, error) {
+	c, err := m.measure()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if m.phases == 1 {
+		return c.VoltageV, 0, 0, nil
+	}
+	return c.VoltageL1V, c.VoltageL2V, c.VoltageL3V, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// batteryPollInterval is how often the battery state is checked while waiting for the device
+const batteryPollInterval = 100 * time.Millisecond
+
+func runBattery(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("expected subcommand: get or set")
+	}
+
+	switch args[0] {
+	case "get":
+		return runBatteryGet(ctx, args[1:])
+	case "set":
+		return runBatterySet(ctx, args[1:])
+	default:
+		return usageError(fmt.Sprintf("unknown subcommand %q, expected get or set", args[0]))
+	}
+}
+
+func runBatteryGet(ctx context.Context, args []string) error {
+	fs := newFlagSet("battery get", "[flags] <host>")
+
+	var (
+		out  outputFlags
+		auth authFlags
+	)
+	out.register(fs)
+	auth.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	d, err := connectP1(ctx, host, auth)
+	if err != nil {
+		return err
+	}
+	defer d.Stop()
+
+	state, err := waitBatteries(ctx, d, nil)
+	if err != nil {
+		return err
+	}
+
+	return out.print(state, func() { printBatteries(state) })
+}
+
+func runBatterySet(ctx context.Context, args []string) error {
+	fs := newFlagSet("battery set", "[flags] <host>")
+
+	var (
+		out         outputFlags
+		auth        authFlags
+		mode        string
+		permissions string
+	)
+	out.register(fs)
+	auth.register(fs)
+	fs.StringVar(&mode, "mode", "", "Battery mode: zero, to_full or standby")
+	fs.StringVar(&permissions, "permissions", "", "Comma separated permissions: charge, discharge, or none")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	if mode == "" && permissions == "" {
+		return usageError("--mode or --permissions required")
+	}
+
+	var m device.BatteryMode
+	if mode != "" {
+		if m, err = device.ParseBatteryMode(mode); err != nil {
+			return usageError(err.Error())
+		}
+	}
+
+	var perms []device.BatteryPermission
+	if permissions != "" {
+		if perms, err = parsePermissions(permissions); err != nil {
+			return usageError(err.Error())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	d, err := connectP1(ctx, host, auth)
+	if err != nil {
+		return err
+	}
+	defer d.Stop()
+
+	// Wait for the current state, so the confirmation below is not the state from before the change
+	if _, err := waitBatteries(ctx, d, nil); err != nil {
+		return err
+	}
+
+	if m != "" {
+		if err := d.SetBatteryMode(m); err != nil {
+			return err
+		}
+	}
+
+	if perms != nil {
+		if err := d.SetBatteryPermissions(perms...); err != nil {
+			return err
+		}
+	}
+
+	state, err := waitBatteries(ctx, d, func(s device.BatteriesState) bool {
+		if m != "" && s.Mode != m {
+			return false
+		}
+		// Firmware without permissions support does not report them
+		if perms != nil && s.Permissions != nil {
+			return slices.Equal(sortedPermissions(s.Permissions), sortedPermissions(perms))
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for confirmation: %w", err)
+	}
+
+	return out.print(state, func() { printBatteries(state) })
+}
+
+// connectP1 connects to the P1 meter controlling the batteries
+func connectP1(ctx context.Context, host string, auth authFlags) (*device.P1MeterDevice, error) {
+	info, err := device.GetInfo(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("device info: %w", err)
+	}
+
+	if p, ok := device.LookupProduct(info.ProductType); !ok || p.DeviceType != device.DeviceTypeP1Meter {
+		return nil, fmt.Errorf("batteries are controlled by the P1 meter, %s is a %s (%s)", host, info.ProductName, info.ProductType)
+	}
+
+	token, err := auth.resolveToken(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	d := device.NewP1MeterDevice(host, token, device.DefaultTimeout)
+	if err := d.StartAndWait(device.DefaultTimeout); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// waitBatteries waits for a battery state accepted by the filter, or any state if the filter is nil
+func waitBatteries(ctx context.Context, d *device.P1MeterDevice, accept func(device.BatteriesState) bool) (device.BatteriesState, error) {
+	ticker := time.NewTicker(batteryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s, err := d.GetBatteries(); err == nil && (accept == nil || accept(s)) {
+			return s, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return device.BatteriesState{}, errors.New("no battery state received")
+		}
+	}
+}
+
+// parsePermissions parses a comma separated list of battery permissions, "none" for an empty list
+func parsePermissions(s string) ([]device.BatteryPermission, error) {
+	res := []device.BatteryPermission{}
+	if s == "none" {
+		return res, nil
+	}
+
+	for _, p := range strings.Split(s, ",") {
+		switch strings.TrimSpace(p) {
+		case "charge", string(device.BatteryPermissionCharge):
+			res = append(res, device.BatteryPermissionCharge)
+		case "discharge", string(device.BatteryPermissionDischarge):
+			res = append(res, device.BatteryPermissionDischarge)
+		default:
+			return nil, fmt.Errorf("invalid battery permission: %s", p)
+		}
+	}
+
+	return res, nil
+}
+
+// sortedPermissions returns a sorted copy of the permissions for comparison
+func sortedPermissions(p []device.BatteryPermission) []device.BatteryPermission {
+	res := slices.Clone(p)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// printBatteries prints the battery state
+func printBatteries(s device.BatteriesState) {
+	w := newTable()
+	fmt.Fprintf(w, "Mode:\t%s\n", s.Mode)
+	if s.Permissions != nil {
+		fmt.Fprintf(w, "Charge allowed:\t%t\n", s.ChargeAllowed())
+		fmt.Fprintf(w, "Discharge allowed:\t%t\n", s.DischargeAllowed())
+	}
+	if s.BatteryCount > 0 {
+		fmt.Fprintf(w, "Batteries:\t%d\n", s.BatteryCount)
+	}
+	fmt.Fprintf(w, "Power:\t%.0f W\n", s.PowerW)
+	fmt.Fprintf(w, "Target power:\t%.0f W\n", s.TargetPowerW)
+	fmt.Fprintf(w, "Max charge:\t%.0f W\n", s.MaxConsumptionW)
+	fmt.Fprintf(w, "Max discharge:\t%.0f W\n", s.MaxProductionW)
+	w.Flush()
+}
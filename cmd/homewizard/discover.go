@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+	"github.com/mluiten/evcc-homewizard-v2/pairing"
+)
+
+// discoveredJSON is the JSON output of a discovered device
+type discoveredJSON struct {
+	Status      discovery.Status  `json:"status"`
+	Instance    string            `json:"instance"`
+	Serial      string            `json:"serial"`
+	Host        string            `json:"host"`
+	Addresses   []string          `json:"addresses,omitempty"`
+	Type        device.DeviceType `json:"type,omitempty"`
+	ProductType string            `json:"product_type"`
+	ProductName string            `json:"product_name"`
+	APIVersion  string            `json:"api_version,omitempty"`
+}
+
+// discoveryFlags configure mDNS discovery or a subnet scan
+type discoveryFlags struct {
+	timeout    time.Duration
+	scan       string
+	interfaces string
+	family     string
+}
+
+func (f *discoveryFlags) register(fs *flag.FlagSet) {
+	fs.DurationVar(&f.timeout, "timeout", 30*time.Second, "Maximum discovery time, mDNS discovery ends earlier once no new devices are found")
+	fs.StringVar(&f.scan, "scan", "", "Scan an IPv4 subnet (CIDR, e.g. 192.168.1.0/24) instead of using mDNS")
+	fs.StringVar(&f.interfaces, "interface", "", "Comma separated network interfaces used for mDNS")
+	fs.StringVar(&f.family, "family", string(discovery.IPFamilyAny), "IP family used for mDNS: any, ipv4 or ipv6")
+}
+
+// discover finds devices, reporting each one once
+func (f *discoveryFlags) discover(ctx context.Context, onEvent func(pairing.Event)) ([]discovery.DiscoveredDevice, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	if f.scan != "" {
+		var res []discovery.DiscoveredDevice
+		err := discovery.ScanSubnet(ctx, f.scan, func(d discovery.DiscoveredDevice) {
+			res = append(res, d)
+			if onEvent != nil {
+				onEvent(pairing.Event{Type: pairing.EventDiscovered, Device: d})
+			}
+		})
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Keep the devices found so far, but don't hide that addresses were skipped
+			fmt.Fprintf(os.Stderr, "Scan of %s incomplete after %v, increase --timeout to probe all addresses\n", f.scan, f.timeout)
+			err = nil
+		}
+		return res, err
+	}
+
+	var opts []discovery.Option
+	if f.interfaces != "" {
+		opts = append(opts, discovery.WithInterfaces(strings.Split(f.interfaces, ",")...))
+	}
+
+	switch family := discovery.IPFamily(f.family); family {
+	case discovery.IPFamilyAny, discovery.IPFamilyV4, discovery.IPFamilyV6:
+		opts = append(opts, discovery.WithIPFamily(family))
+	default:
+		return nil, usageError(fmt.Sprintf("invalid IP family: %s", f.family))
+	}
+
+	return pairing.Discover(ctx, onEvent, opts...)
+}
+
+func runDiscover(ctx context.Context, args []string) error {
+	fs := newFlagSet("discover", "[flags]")
+
+	var (
+		out outputFlags
+		df  discoveryFlags
+	)
+	out.register(fs)
+	df.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected arguments")
+	}
+
+	if !out.json {
+		fmt.Fprintln(os.Stderr, "Discovering devices...")
+	}
+
+	devices, err := df.discover(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	res := make([]discoveredJSON, 0, len(devices))
+	for _, d := range devices {
+		res = append(res, discoveredJSON{
+			Status:      d.Status,
+			Instance:    d.Instance,
+			Serial:      d.Serial,
+			Host:        d.Host,
+			Addresses:   d.Addresses,
+			Type:        d.Type,
+			ProductType: d.ProductType,
+			ProductName: d.ProductName,
+			APIVersion:  d.APIVersion,
+		})
+	}
+
+	return out.print(res, func() {
+		if len(res) == 0 {
+			fmt.Println("No devices found")
+			return
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "HOST\tPRODUCT\tTYPE\tSERIAL\tSTATUS")
+		for _, d := range res {
+			fmt.Fprintf(w, "%s\t%s (%s)\t%s\t%s\t%s\n", d.Host, d.ProductName, d.ProductType, d.Type, d.Serial, d.Status)
+		}
+		w.Flush()
+	})
+}
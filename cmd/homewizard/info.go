@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// infoJSON is the JSON output of the info command
+type infoJSON struct {
+	Host   string             `json:"host"`
+	Device device.DeviceInfo  `json:"device"`
+	System *device.SystemInfo `json:"system,omitempty"` // Only with a token
+}
+
+func runInfo(ctx context.Context, args []string) error {
+	fs := newFlagSet("info", "[flags] <host>")
+
+	var (
+		out  outputFlags
+		auth authFlags
+	)
+	out.register(fs)
+	auth.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	info, err := device.GetInfo(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	res := infoJSON{Host: host, Device: info}
+
+	// System information requires a token
+	if auth.configured() {
+		token, err := auth.resolveToken(ctx, host)
+		if err != nil {
+			return err
+		}
+
+		system, err := device.GetSystem(ctx, host, token)
+		if err != nil {
+			return fmt.Errorf("system info: %w", err)
+		}
+		res.System = &system
+	}
+
+	return out.print(res, func() {
+		w := newTable()
+		fmt.Fprintf(w, "Host:\t%s\n", host)
+		fmt.Fprintf(w, "Product:\t%s (%s)\n", info.ProductName, info.ProductType)
+		fmt.Fprintf(w, "Serial:\t%s\n", info.Serial)
+		fmt.Fprintf(w, "Firmware:\t%s\n", info.FirmwareVersion)
+		fmt.Fprintf(w, "API version:\t%s\n", info.APIVersion)
+
+		if s := res.System; s != nil {
+			fmt.Fprintf(w, "WiFi:\t%s (%.0f dB)\n", s.WifiSSID, s.WifiRSSIdB)
+			fmt.Fprintf(w, "Uptime:\t%v\n", time.Duration(s.UptimeS)*time.Second)
+			fmt.Fprintf(w, "Cloud:\t%s\n", enabled(s.CloudEnabled))
+			fmt.Fprintf(w, "Status LED:\t%d%%\n", s.StatusLEDBrightnessPct)
+			if s.APIV1Enabled != nil {
+				fmt.Fprintf(w, "API v1:\t%s\n", enabled(*s.APIV1Enabled))
+			}
+		}
+		w.Flush()
+	})
+}
+
+// enabled formats a boolean setting
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,271 @@
+// Command homewizard discovers, pairs and inspects HomeWizard Energy devices using the local API v2
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/evcc-io/evcc/util"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// Environment variables used as flag defaults
+const (
+	envToken      = "HOMEWIZARD_TOKEN"
+	envStore      = "HOMEWIZARD_STORE"
+	envPassphrase = "HOMEWIZARD_STORE_PASSPHRASE"
+	envLogLevel   = "HOMEWIZARD_LOG_LEVEL"
+)
+
+// requestTimeout limits one-shot requests to a device
+const requestTimeout = 30 * time.Second
+
+// command is a subcommand of the CLI
+type command struct {
+	name string
+	args string
+	help string
+	run  func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "discover", args: "[flags]", help: "Discover devices via mDNS or a subnet scan", run: runDiscover},
+	{name: "pair", args: "[flags] [host...]", help: "Pair devices and print or save their tokens", run: runPair},
+	{name: "info", args: "[flags] <host>", help: "Show device and system information", run: runInfo},
+	{name: "monitor", args: "[flags] <host>", help: "Print WebSocket messages of a device", run: runMonitor},
+	{name: "battery", args: "get|set", help: "Show or control the batteries of a P1 meter", run: runBattery},
+	{name: "users", args: "list|revoke", help: "List or revoke API users of a device", run: runUsers},
+	{name: "identify", args: "[flags] <host>", help: "Blink the status LED of a device", run: runIdentify},
+	{name: "reboot", args: "[flags] <host>", help: "Reboot a device", run: runReboot},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "homewizard: unknown command %q\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
+
+	setupLogging()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "homewizard %s: %v\n", cmd.name, err)
+
+		var uerr usageError
+		if errors.As(err, &uerr) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// setupLogging silences the library logs unless a log level is set, as device logs are written to stdout
+func setupLogging() {
+	level := os.Getenv(envLogLevel)
+	if level == "" {
+		util.LogLevel("fatal", nil)
+		log.SetOutput(io.Discard)
+		return
+	}
+
+	util.LogLevel(level, nil)
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage prints the list of subcommands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: homewizard <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", c.name, c.args, c.help)
+	}
+	w.Flush()
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "homewizard <command> -h" for the flags of a command.`)
+	fmt.Fprintf(os.Stderr, "Tokens are taken from --token or %s, or looked up by serial in the token store\n", envToken)
+	fmt.Fprintf(os.Stderr, "given by --store or %s, encrypted with the passphrase in %s.\n", envStore, envPassphrase)
+	fmt.Fprintf(os.Stderr, "Set %s (e.g. debug) to show device logs.\n", envLogLevel)
+}
+
+// usageError is returned for invalid arguments, exiting with status 2
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: homewizard %s %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags that may appear before, between or after the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// hostArg returns the single host argument, stripping a URL scheme
+func hostArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageError("expected exactly one host argument")
+	}
+	return trimScheme(args[0]), nil
+}
+
+// trimScheme strips a URL scheme from a host
+func trimScheme(host string) string {
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.TrimPrefix(host, "https://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// outputFlags selects the output format
+type outputFlags struct {
+	json bool
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.json, "json", false, "Print JSON output for scripting")
+}
+
+// print writes v as indented JSON, or calls text for human readable output
+func (o *outputFlags) print(v any, text func()) error {
+	if !o.json {
+		text()
+		return nil
+	}
+	return printJSON(v)
+}
+
+// printJSON writes v as indented JSON to stdout
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newTable creates a tab aligned writer for table output, call Flush when done
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// storeFlags selects the encrypted token store
+type storeFlags struct {
+	store string
+}
+
+func (s *storeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.store, "store", os.Getenv(envStore), "Encrypted token store file, tokens are looked up and saved by serial")
+}
+
+// open opens the token store, nil if not configured
+func (s *storeFlags) open() (*device.FileTokenStore, error) {
+	if s.store == "" {
+		return nil, nil
+	}
+
+	passphrase := os.Getenv(envPassphrase)
+	if passphrase == "" {
+		return nil, fmt.Errorf("token store requires a passphrase in %s", envPassphrase)
+	}
+
+	return device.NewFileTokenStore(s.store, passphrase)
+}
+
+// authFlags select the token of the device
+type authFlags struct {
+	storeFlags
+	token string
+}
+
+func (a *authFlags) register(fs *flag.FlagSet) {
+	a.storeFlags.register(fs)
+	fs.StringVar(&a.token, "token", os.Getenv(envToken), "Device token")
+}
+
+// configured returns true if a token or token store is given
+func (a *authFlags) configured() bool {
+	return a.token != "" || a.store != ""
+}
+
+// resolveToken returns the token given as flag, or looks up the token of the device's serial in the store
+func (a *authFlags) resolveToken(ctx context.Context, host string) (string, error) {
+	if a.token != "" {
+		return a.token, nil
+	}
+
+	store, err := a.open()
+	if err != nil {
+		return "", err
+	}
+	if store == nil {
+		return "", fmt.Errorf("token required, use --token, %s or --store", envToken)
+	}
+
+	info, err := device.GetInfo(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("device info: %w", err)
+	}
+
+	return store.Token(info.Serial)
+}
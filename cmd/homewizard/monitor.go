@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// messageJSON is the JSON output of a WebSocket message, one per line
+type messageJSON struct {
+	Time time.Time       `json:"time"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func runMonitor(ctx context.Context, args []string) error {
+	fs := newFlagSet("monitor", "[flags] <host>")
+
+	var (
+		out      outputFlags
+		auth     authFlags
+		topics   string
+		duration time.Duration
+	)
+	out.register(fs)
+	auth.register(fs)
+	fs.StringVar(&topics, "topics", "", "Comma separated WebSocket topics, defaults to the topics of the product")
+	fs.DurationVar(&duration, "duration", 0, "Stop after this duration, runs until interrupted by default")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.resolveToken(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	subscribe, err := monitorTopics(ctx, host, topics)
+	if err != nil {
+		return err
+	}
+
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(os.Stdout)
+
+	conn := device.NewConnection(host, token, func(msgType string, data json.RawMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if out.json {
+			return enc.Encode(messageJSON{Time: now, Type: msgType, Data: data})
+		}
+
+		fmt.Printf("%s %s %s\n", now.Format(time.TimeOnly), msgType, data)
+		return nil
+	}, subscribe...)
+
+	errC := make(chan error, 1)
+	conn.Start(errC)
+	defer conn.Stop()
+
+	// The error channel is closed once connected and authenticated
+	select {
+	case err := <-errC:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return nil
+	}
+
+	if !out.json {
+		fmt.Fprintf(os.Stderr, "Monitoring %s (%s), press Ctrl+C to stop\n", host, strings.Join(subscribe, ", "))
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// monitorTopics returns the given topics, or the default topics of the device's product
+func monitorTopics(ctx context.Context, host, topics string) ([]string, error) {
+	if topics != "" {
+		return strings.Split(topics, ","), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	info, err := device.GetInfo(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("device info: %w", err)
+	}
+
+	p, ok := device.LookupProduct(info.ProductType)
+	if !ok || len(p.Topics) == 0 {
+		return []string{"measurement"}, nil
+	}
+
+	return p.Topics, nil
+}
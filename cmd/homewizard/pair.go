@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+	"github.com/mluiten/evcc-homewizard-v2/pairing"
+)
+
+// pairedJSON is the JSON output of a paired device with its default evcc meter
+type pairedJSON struct {
+	Host            string            `json:"host"`
+	Token           string            `json:"token"`
+	Type            device.DeviceType `json:"type"`
+	ProductType     string            `json:"product_type"`
+	ProductName     string            `json:"product_name"`
+	Serial          string            `json:"serial"`
+	FirmwareVersion string            `json:"firmware_version"`
+	Meter           string            `json:"meter,omitempty"`
+	Usage           adapter.Usage     `json:"usage,omitempty"`
+}
+
+func runPair(ctx context.Context, args []string) error {
+	fs := newFlagSet("pair", "[flags] [host...]")
+
+	var (
+		out         outputFlags
+		df          discoveryFlags
+		sf          storeFlags
+		name        string
+		interactive bool
+		configFile  string
+		format      string
+		site        bool
+	)
+	out.register(fs)
+	df.register(fs)
+	sf.register(fs)
+	fs.StringVar(&name, "name", "evcc", "User name registered on the device")
+	fs.BoolVar(&interactive, "interactive", false, "Select devices and assign meter roles interactively")
+	fs.StringVar(&configFile, "config", "", "Merge the meters into an existing evcc.yaml")
+	fs.StringVar(&format, "format", string(pairing.ConfigFormatYAML), "Config format printed in interactive mode: yaml or json")
+	fs.BoolVar(&site, "site", false, "Add a site section to the config printed in interactive mode")
+
+	hosts, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := pairing.ValidateName(name); err != nil {
+		return usageError(err.Error())
+	}
+
+	store, err := sf.open()
+	if err != nil {
+		return err
+	}
+
+	var opts []pairing.Option
+	if store != nil {
+		opts = append(opts, pairing.WithTokenStore(store))
+	}
+
+	if interactive {
+		if out.json {
+			return usageError("--json cannot be used with --interactive")
+		}
+		return pairInteractively(ctx, hosts, name, df, configFile, format, site, opts)
+	}
+
+	devices := make([]discovery.DiscoveredDevice, 0, len(hosts))
+	for _, host := range hosts {
+		devices = append(devices, discovery.DiscoveredDevice{Host: trimScheme(host)})
+	}
+
+	if len(devices) == 0 {
+		fmt.Fprintln(os.Stderr, "Discovering devices...")
+
+		found, err := df.discover(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range found {
+			if d.Status != discovery.StatusReady {
+				fmt.Fprintf(os.Stderr, "Skipping %s at %s: %s\n", d.ProductName, d.Host, d.Status)
+				continue
+			}
+			devices = append(devices, d)
+		}
+
+		if len(devices) == 0 {
+			return errors.New("no devices found")
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Pairing %d device(s), press the button on each device within %v\n", len(devices), pairing.PairTimeout)
+
+	paired, pairErr := pairing.Pair(ctx, devices, name, printPairEvent, opts...)
+
+	meters := pairing.DefaultMeters(paired)
+
+	if configFile != "" && len(meters) > 0 {
+		res, err := pairing.MergeConfigFile(configFile, pairing.NewConfig(meters, false))
+		if err != nil {
+			return errors.Join(pairErr, fmt.Errorf("updating %s: %w", configFile, err))
+		}
+		fmt.Fprintf(os.Stderr, "Updated %s: %d meter(s) updated, %d added\n", configFile, len(res.Updated), len(res.Added))
+	}
+
+	res := make([]pairedJSON, 0, len(meters))
+	for _, m := range meters {
+		d := m.Device
+		res = append(res, pairedJSON{
+			Host:            d.Host,
+			Token:           d.Token,
+			Type:            d.Type,
+			ProductType:     d.ProductType,
+			ProductName:     d.ProductName,
+			Serial:          d.Serial,
+			FirmwareVersion: d.FirmwareVersion,
+			Meter:           m.Name,
+			Usage:           m.Usage,
+		})
+	}
+
+	if err := out.print(res, func() {
+		if len(res) == 0 {
+			return
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "HOST\tPRODUCT\tSERIAL\tMETER\tTOKEN")
+		for _, d := range res {
+			fmt.Fprintf(w, "%s\t%s (%s)\t%s\t%s\t%s\n", d.Host, d.ProductName, d.ProductType, d.Serial, d.Meter, d.Token)
+		}
+		w.Flush()
+	}); err != nil {
+		return err
+	}
+
+	return pairErr
+}
+
+// pairInteractively runs the interactive terminal flow of the pairing package
+func pairInteractively(ctx context.Context, hosts []string, name string, df discoveryFlags, configFile, format string, site bool, opts []pairing.Option) error {
+	f, err := pairing.ParseConfigFormat(format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	opts = append(opts, pairing.WithConfigFormat(f))
+
+	if site {
+		opts = append(opts, pairing.WithSite())
+	}
+	if configFile != "" {
+		opts = append(opts, pairing.WithConfigFile(configFile))
+	}
+
+	switch len(hosts) {
+	case 0:
+		// Discover with the subnet scan or mDNS options given as flags
+		opts = append(opts, pairing.WithDiscovery(df.discover))
+		return pairing.DiscoverAndPairDevices(ctx, name, int(df.timeout.Seconds()), opts...)
+	case 1:
+		return pairing.PairSingleDevice(ctx, trimScheme(hosts[0]), name, opts...)
+	default:
+		return usageError("interactive pairing accepts at most one host")
+	}
+}
+
+// printPairEvent reports pairing progress on stderr
+func printPairEvent(e pairing.Event) {
+	host := e.Device.Host
+	if e.Device.ProductName != "" {
+		host = fmt.Sprintf("%s (%s)", e.Device.ProductName, host)
+	}
+
+	switch e.Type {
+	case pairing.EventWaitingForButton:
+		if e.Attempt == 1 {
+			fmt.Fprintf(os.Stderr, "%s: waiting for button press\n", host)
+		}
+	case pairing.EventPaired:
+		fmt.Fprintf(os.Stderr, "%s: paired, serial %s\n", host, e.Paired.Serial)
+	case pairing.EventFailed:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", host, strings.TrimSpace(e.Err.Error()))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// actionJSON is the JSON output of a device action
+type actionJSON struct {
+	Host   string `json:"host"`
+	Action string `json:"action"`
+}
+
+func runIdentify(ctx context.Context, args []string) error {
+	return runAction(ctx, "identify", args, device.Identify, "Status LED of %s is blinking\n")
+}
+
+func runReboot(ctx context.Context, args []string) error {
+	return runAction(ctx, "reboot", args, device.Reboot, "Rebooting %s\n")
+}
+
+// runAction runs a system action without parameters on a device
+func runAction(ctx context.Context, name string, args []string, action func(ctx context.Context, host, token string) error, done string) error {
+	fs := newFlagSet(name, "[flags] <host>")
+
+	var (
+		out  outputFlags
+		auth authFlags
+	)
+	out.register(fs)
+	auth.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	token, err := auth.resolveToken(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	if err := action(ctx, host, token); err != nil {
+		return err
+	}
+
+	return out.print(actionJSON{Host: host, Action: name}, func() {
+		fmt.Printf(done, host)
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+func runUsers(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("expected subcommand: list or revoke")
+	}
+
+	switch args[0] {
+	case "list":
+		return runUsersList(ctx, args[1:])
+	case "revoke":
+		return runUsersRevoke(ctx, args[1:])
+	default:
+		return usageError(fmt.Sprintf("unknown subcommand %q, expected list or revoke", args[0]))
+	}
+}
+
+func runUsersList(ctx context.Context, args []string) error {
+	fs := newFlagSet("users list", "[flags] <host>")
+
+	var (
+		out  outputFlags
+		auth authFlags
+	)
+	out.register(fs)
+	auth.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	host, err := hostArg(rest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	token, err := auth.resolveToken(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	users, err := device.ListUsers(ctx, host, token)
+	if err != nil {
+		return err
+	}
+
+	return out.print(users, func() {
+		w := newTable()
+		fmt.Fprintln(w, "NAME\tCURRENT")
+		for _, u := range users {
+			current := ""
+			if u.Current {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", u.Name, current)
+		}
+		w.Flush()
+	})
+}
+
+func runUsersRevoke(ctx context.Context, args []string) error {
+	fs := newFlagSet("users revoke", "[flags] <host> <name>")
+
+	var (
+		out  outputFlags
+		auth authFlags
+	)
+	out.register(fs)
+	auth.register(fs)
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 2 {
+		return usageError("expected host and user name arguments")
+	}
+
+	host := trimScheme(rest[0])
+
+	// User names are reported with the local/ prefix added during pairing
+	name := rest[1]
+	if !strings.Contains(name, "/") {
+		name = "local/" + name
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	token, err := auth.resolveToken(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	users, err := device.ListUsers(ctx, host, token)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(users, func(u device.User) bool { return u.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("user %s not found", name)
+	}
+	if users[idx].Current {
+		return errors.New("cannot revoke the user of the token in use, use the token of another user")
+	}
+
+	if err := device.DeleteUser(ctx, host, token, name); err != nil {
+		return err
+	}
+
+	return out.print(users[idx], func() {
+		fmt.Printf("Revoked %s on %s\n", name, host)
+	})
+}
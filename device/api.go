@@ -0,0 +1,50 @@
+package device
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/evcc-io/evcc/util"
+	"github.com/evcc-io/evcc/util/request"
+	"github.com/evcc-io/evcc/util/transport"
+)
+
+// apiTimeout limits a single HTTP request to the local API
+const apiTimeout = 10 * time.Second
+
+// NewHTTPClient creates an HTTP client for the local API
+// Devices use self-signed certificates, so the certificate is not verified
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: transport.Insecure(),
+		Timeout:   timeout,
+	}
+}
+
+// NewAPIRequest creates a JSON request for the local API v2
+// The request is unauthenticated if the token is empty.
+func NewAPIRequest(ctx context.Context, method, uri, token string, body io.Reader) (*http.Request, error) {
+	req, err := request.New(method, uri, body, request.JSONEncoding)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	// Set required headers for HomeWizard API v2
+	req.Header.Set("X-Api-Version", "2")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// newAPIHelper creates a request helper with the HTTP client of NewHTTPClient
+func newAPIHelper(log *util.Logger) *request.Helper {
+	helper := request.NewHelper(log)
+	helper.Client.Transport = transport.Insecure()
+	helper.Client.Timeout = apiTimeout
+	return helper
+}
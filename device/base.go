@@ -6,24 +6,26 @@ import (
 
 	"github.com/evcc-io/evcc/util"
 	"github.com/evcc-io/evcc/util/request"
-	"github.com/evcc-io/evcc/util/transport"
 )
 
 // DeviceType identifies the type of HomeWizard device
 type DeviceType string
 
 const (
-	DeviceTypeP1Meter  DeviceType = "p1meter"
-	DeviceTypeKWHMeter DeviceType = "kwhmeter"
-	DeviceTypeBattery  DeviceType = "battery"
+	DeviceTypeP1Meter      DeviceType = "p1meter"
+	DeviceTypeKWHMeter     DeviceType = "kwhmeter"
+	DeviceTypeBattery      DeviceType = "battery"
+	DeviceTypeEnergySocket DeviceType = "energysocket"
+	DeviceTypeWaterMeter   DeviceType = "watermeter"
 )
 
 // Default configuration values
 const (
 	DefaultTimeout         = 30 * time.Second
-	DefaultMaxCharge       = 800.0 // W - Default charge limit for HWE-BAT
-	DefaultMaxDischarge    = 800.0 // W - Default discharge limit for HWE-BAT
-	DefaultBatteryCapacity = 2.47  // kWh - HWE-BAT capacity
+	DefaultWaterTimeout    = time.Hour // Battery powered watermeters only report when they wake up
+	DefaultMaxCharge       = 800.0     // W - Default charge limit for HWE-BAT
+	DefaultMaxDischarge    = 800.0     // W - Default discharge limit for HWE-BAT
+	DefaultBatteryCapacity = 2.47      // kWh - HWE-BAT capacity
 )
 
 // deviceBase contains common functionality for all HomeWizard devices
@@ -35,30 +37,31 @@ type deviceBase struct {
 	log        *util.Logger
 	conn       *Connection
 	timeout    time.Duration
+	decode     decoder // Measurement decoder, New uses the decoder of the product
 }
 
-// newDeviceBase creates a new base device with common fields
-func newDeviceBase(deviceType DeviceType, host, token string, timeout time.Duration) *deviceBase {
+// newDeviceBase creates a new base device with common fields and the default measurement decoder
+func newDeviceBase(deviceType DeviceType, decode decoder, host, token string, timeout time.Duration) *deviceBase {
 	log := util.NewLogger("homewizard-v2").Redact(token)
 
 	d := &deviceBase{
-		Helper:     request.NewHelper(log),
+		Helper:     newAPIHelper(log),
 		deviceType: deviceType,
 		host:       host,
 		token:      token,
 		log:        log,
 		timeout:    timeout,
+		decode:     decode,
 	}
 
-	// Use insecure HTTPS transport for self-signed certificates
-	d.Client.Transport = transport.Insecure()
-
-	// Set timeout for HTTP requests
-	d.Client.Timeout = 10 * time.Second
-
 	return d
 }
 
+// setDecoder replaces the measurement decoder, must be called before Start
+func (d *deviceBase) setDecoder(decode decoder) {
+	d.decode = decode
+}
+
 // Type returns the device type
 func (d *deviceBase) Type() DeviceType {
 	return d.deviceType
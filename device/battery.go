@@ -28,21 +28,38 @@ type BatteryMeasurement struct {
 	Cycles           int     `json:"cycles"`
 }
 
+// batteryTopics are the WebSocket topics of batteries
+var batteryTopics = []string{"measurement"}
+
+func init() {
+	RegisterProduct(Product{
+		ProductType: "HWE-BAT",
+		DeviceType:  DeviceTypeBattery,
+		Usage:       "battery",
+		Topics:      batteryTopics,
+		Decode:      decodeAs[BatteryMeasurement](),
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewBatteryDevice(host, token, timeout)
+		},
+	})
+}
+
 // BatteryDevice represents a battery (HWE-BAT) for SoC and power monitoring
 type BatteryDevice struct {
 	*deviceBase
 	measurement *util.Monitor[BatteryMeasurement]
+	estimator   *CapacityEstimator
 }
 
 // NewBatteryDevice creates a new battery device instance
 func NewBatteryDevice(host, token string, timeout time.Duration) *BatteryDevice {
 	d := &BatteryDevice{
-		deviceBase:  newDeviceBase(DeviceTypeBattery, host, token, timeout),
+		deviceBase:  newDeviceBase(DeviceTypeBattery, decodeAs[BatteryMeasurement](), host, token, timeout),
 		measurement: util.NewMonitor[BatteryMeasurement](timeout),
 	}
 
 	// Create connection with message handler
-	d.conn = NewConnection(host, token, d.handleMessage)
+	d.conn = NewConnection(host, token, d.handleMessage, batteryTopics...)
 
 	return d
 }
@@ -51,13 +68,19 @@ func NewBatteryDevice(host, token string, timeout time.Duration) *BatteryDevice
 func (d *BatteryDevice) handleMessage(msgType string, data json.RawMessage) error {
 	switch msgType {
 	case "measurement":
-		var m BatteryMeasurement
-		if err := json.Unmarshal(data, &m); err != nil {
+		m, err := decodeMeasurement[BatteryMeasurement](d.decode, data)
+		if err != nil {
 			return fmt.Errorf("unmarshal battery measurement: %w", err)
 		}
 		d.measurement.Set(m)
 		d.log.TRACE.Printf("updated battery measurement: soc=%.1f%%, power=%.1fW", m.StateOfChargePct, m.PowerW)
 
+		if d.estimator != nil {
+			if err := d.estimator.Observe(m); err != nil {
+				d.log.ERROR.Printf("capacity estimate: %v", err)
+			}
+		}
+
 	case "device", "system", "user":
 		// Ignore device info, system messages, and user messages
 		d.log.TRACE.Printf("ignoring message type: %s", msgType)
@@ -83,6 +106,29 @@ func (d *BatteryDevice) DefaultCapacity() float64 {
 	return DefaultBatteryCapacity
 }
 
+// SetCapacityEstimator enables learning the usable capacity from measurements
+// Should be called before Start
+func (d *BatteryDevice) SetCapacityEstimator(e *CapacityEstimator) {
+	d.estimator = e
+}
+
+// Capacity returns the learned usable capacity in kWh, or the default capacity without estimator
+func (d *BatteryDevice) Capacity() float64 {
+	if d.estimator == nil {
+		return d.DefaultCapacity()
+	}
+	return d.estimator.Capacity()
+}
+
+// GetStateOfHealth returns the learned state of health in percent
+// Returns api.ErrNotAvailable without estimator or if nothing was learned yet
+func (d *BatteryDevice) GetStateOfHealth() (float64, error) {
+	if d.estimator == nil {
+		return 0, api.ErrNotAvailable
+	}
+	return d.estimator.StateOfHealth()
+}
+
 // GetPower returns the battery power (inverted: negative = discharging, positive = charging)
 func (d *BatteryDevice) GetPower() (float64, error) {
 	m, err := d.GetMeasurement()
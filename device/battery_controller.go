@@ -0,0 +1,125 @@
+package device
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+// BatteryController maps evcc battery modes onto the P1 batteries API
+// It implements api.BatteryController and restores the user's mode when evcc releases control
+type BatteryController struct {
+	mu       sync.Mutex
+	p1       *P1MeterDevice
+	mode     api.BatteryMode
+	original *BatteriesState // State before evcc took control, nil if not controlled
+}
+
+var _ api.BatteryController = (*BatteryController)(nil)
+
+// NewBatteryController creates a battery controller on top of a P1 meter
+func NewBatteryController(p1 *P1MeterDevice) *BatteryController {
+	return &BatteryController{
+		p1:   p1,
+		mode: api.BatteryNormal,
+	}
+}
+
+// batteryModes maps evcc battery modes to HomeWizard battery modes
+var batteryModes = map[api.BatteryMode]BatteryMode{
+	api.BatteryNormal: BatteryModeZero,
+	api.BatteryHold:   BatteryModeStandby,
+	api.BatteryCharge: BatteryModeToFull,
+}
+
+// SetBatteryMode implements the api.BatteryController interface
+func (c *BatteryController) SetBatteryMode(mode api.BatteryMode) error {
+	target, ok := batteryModes[mode]
+	if !ok {
+		return fmt.Errorf("invalid battery mode: %s", mode)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	switch {
+	case mode == api.BatteryNormal && c.original != nil:
+		// Release control, restoring exactly the user's mode and permissions
+		err = c.p1.setBatteries(restoreRequest(*c.original))
+
+	case mode != api.BatteryNormal && c.mode == api.BatteryNormal:
+		// Taking control, remember the user's state, including modes unknown to this library
+		if b, err := c.p1.GetBatteries(); err == nil && b.Mode != "" {
+			c.original = &b
+		}
+		fallthrough
+
+	default:
+		err = c.p1.SetBatteryMode(target)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.mode = mode
+	if mode == api.BatteryNormal {
+		c.original = nil
+	}
+
+	return nil
+}
+
+// restoreRequest returns the request restoring a captured battery state
+// Permissions are only sent if the firmware reported them
+func restoreRequest(s BatteriesState) batteriesRequest {
+	req := batteriesRequest{Mode: s.Mode}
+	if s.Permissions != nil {
+		permissions := slices.Clone(s.Permissions)
+		req.Permissions = &permissions
+	}
+	return req
+}
+
+// Mode returns the battery mode last requested by evcc
+func (c *BatteryController) Mode() api.BatteryMode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mode
+}
+
+// OriginalMode returns the user's mode that will be restored when evcc releases control
+// Returns false if evcc is not in control or the mode could not be captured
+func (c *BatteryController) OriginalMode() (BatteryMode, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.original == nil {
+		return "", false
+	}
+	return c.original.Mode, true
+}
+
+// OriginalPermissions returns the user's permissions that will be restored when evcc releases control
+// Returns nil if evcc is not in control or the firmware does not support permissions
+func (c *BatteryController) OriginalPermissions() []BatteryPermission {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.original == nil {
+		return nil
+	}
+	return slices.Clone(c.original.Permissions)
+}
+
+// EffectiveMode returns the battery mode currently reported by the P1 meter
+func (c *BatteryController) EffectiveMode() (BatteryMode, error) {
+	b, err := c.p1.GetBatteries()
+	if err != nil {
+		return "", err
+	}
+	return b.Mode, nil
+}
@@ -0,0 +1,30 @@
+package device
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRestoreRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		state BatteriesState
+		want  string
+	}{
+		{"unknown mode", BatteriesState{Mode: "predictive"}, `{"mode":"predictive"}`},
+		{"charge only", BatteriesState{Mode: BatteryModeZero, Permissions: []BatteryPermission{BatteryPermissionCharge}}, `{"mode":"zero","permissions":["charge_allowed"]}`},
+		{"no permissions", BatteriesState{Mode: BatteryModeZero, Permissions: []BatteryPermission{}}, `{"mode":"zero","permissions":[]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(restoreRequest(tc.state))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("got %s, want %s", b, tc.want)
+			}
+		})
+	}
+}
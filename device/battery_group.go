@@ -0,0 +1,161 @@
+package device
+
+import (
+	"math"
+	"sync"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+// BatteryGroupMeasurement contains aggregated measurements of a battery group
+type BatteryGroupMeasurement struct {
+	BatteryMeasurement // Aggregated over all online units
+
+	CapacitykWh float64 // Combined capacity of the online units
+	Online      int     // Number of units that reported data
+	Total       int     // Number of units in the group
+	Partial     bool    // At least one unit is offline, values cover the online units only
+}
+
+// batteryUnit is a single battery that can be part of a group
+type batteryUnit interface {
+	Host() string
+	GetMeasurement() (BatteryMeasurement, error)
+	Capacity() float64
+}
+
+// BatteryGroup combines several batteries (HWE-BAT) into one logical battery
+// The batteries must be started individually before the group is used
+type BatteryGroup struct {
+	mu         sync.Mutex
+	units      []batteryUnit
+	lastImport []float64 // Last known energy counters, used while a unit is offline
+	lastExport []float64
+}
+
+// NewBatteryGroup creates a logical battery from several battery devices
+func NewBatteryGroup(batteries ...*BatteryDevice) *BatteryGroup {
+	units := make([]batteryUnit, 0, len(batteries))
+	for _, b := range batteries {
+		units = append(units, b)
+	}
+
+	return &BatteryGroup{
+		units:      units,
+		lastImport: make([]float64, len(units)),
+		lastExport: make([]float64, len(units)),
+	}
+}
+
+// GetMeasurement returns the aggregated measurement of all online units
+// SoC is weighted by capacity, power and current are summed, voltage and frequency are averaged
+// Energy counters include the last known values of offline units so they never decrease
+// Returns api.ErrTimeout only if no unit reports data
+func (g *BatteryGroup) GetMeasurement() (BatteryGroupMeasurement, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	res := BatteryGroupMeasurement{
+		Total: len(g.units),
+	}
+
+	var socWeighted, cycles float64
+
+	for i, u := range g.units {
+		m, err := u.GetMeasurement()
+		if err != nil {
+			res.EnergyImportkWh += g.lastImport[i]
+			res.EnergyExportkWh += g.lastExport[i]
+			continue
+		}
+
+		g.lastImport[i] = m.EnergyImportkWh
+		g.lastExport[i] = m.EnergyExportkWh
+
+		capacity := u.Capacity()
+
+		res.Online++
+		res.CapacitykWh += capacity
+		res.EnergyImportkWh += m.EnergyImportkWh
+		res.EnergyExportkWh += m.EnergyExportkWh
+		res.PowerW += m.PowerW
+		res.CurrentA += m.CurrentA
+		res.VoltageV += m.VoltageV
+		res.FrequencyHz += m.FrequencyHz
+		socWeighted += m.StateOfChargePct * capacity
+		cycles += float64(m.Cycles)
+	}
+
+	if res.Online == 0 {
+		return BatteryGroupMeasurement{}, api.ErrTimeout
+	}
+
+	res.Partial = res.Online < res.Total
+	res.VoltageV /= float64(res.Online)
+	res.FrequencyHz /= float64(res.Online)
+	res.Cycles = int(math.Round(cycles / float64(res.Online)))
+	if res.CapacitykWh > 0 {
+		res.StateOfChargePct = socWeighted / res.CapacitykWh
+	}
+
+	return res, nil
+}
+
+// Size returns the number of units in the group
+func (g *BatteryGroup) Size() int {
+	return len(g.units)
+}
+
+// Capacity returns the combined capacity in kWh
+// While units are offline only the online capacity is returned, matching the SoC of the online units
+func (g *BatteryGroup) Capacity() float64 {
+	if m, err := g.GetMeasurement(); err == nil {
+		return m.CapacitykWh
+	}
+
+	// No unit online, report the nominal capacity
+	var capacity float64
+	for _, u := range g.units {
+		capacity += u.Capacity()
+	}
+	return capacity
+}
+
+// GetPower returns the combined battery power (inverted: positive = discharging, negative = charging)
+func (g *BatteryGroup) GetPower() (float64, error) {
+	m, err := g.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	// HW reports negative = discharging and positive = charging, evcc expects the opposite
+	return -m.PowerW, nil
+}
+
+// GetSoc returns the capacity-weighted state of charge of the online units
+func (g *BatteryGroup) GetSoc() (float64, error) {
+	m, err := g.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return m.StateOfChargePct, nil
+}
+
+// GetTotalEnergy returns the combined imported energy
+func (g *BatteryGroup) GetTotalEnergy() (float64, error) {
+	m, err := g.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return m.EnergyImportkWh, nil
+}
+
+// OfflineHosts returns the hosts of units that currently do not report data
+func (g *BatteryGroup) OfflineHosts() []string {
+	var res []string
+	for _, u := range g.units {
+		if _, err := u.GetMeasurement(); err != nil {
+			res = append(res, u.Host())
+		}
+	}
+	return res
+}
@@ -0,0 +1,71 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+// fakeBatteryUnit is a battery stub for the group
+type fakeBatteryUnit struct {
+	m        BatteryMeasurement
+	offline  bool
+	capacity float64
+}
+
+func (u *fakeBatteryUnit) Host() string { return "fake" }
+
+func (u *fakeBatteryUnit) GetMeasurement() (BatteryMeasurement, error) {
+	if u.offline {
+		return BatteryMeasurement{}, api.ErrTimeout
+	}
+	return u.m, nil
+}
+
+func (u *fakeBatteryUnit) Capacity() float64 { return u.capacity }
+
+func TestBatteryGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		offline  []bool
+		soc      float64
+		capacity float64
+		partial  bool
+	}{
+		{"all online", []bool{false, false}, 50, 3, false},
+		{"one offline", []bool{false, true}, 80, 1, true},
+		{"all offline", []bool{true, true}, 0, 3, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			units := []*fakeBatteryUnit{
+				{m: BatteryMeasurement{StateOfChargePct: 80}, capacity: 1},
+				{m: BatteryMeasurement{StateOfChargePct: 35}, capacity: 2},
+			}
+			g := &BatteryGroup{lastImport: make([]float64, len(units)), lastExport: make([]float64, len(units))}
+			for i, u := range units {
+				u.offline = tc.offline[i]
+				g.units = append(g.units, u)
+			}
+
+			if c := g.Capacity(); c != tc.capacity {
+				t.Errorf("capacity = %v, want %v", c, tc.capacity)
+			}
+
+			m, err := g.GetMeasurement()
+			if tc.soc == 0 {
+				if err == nil {
+					t.Error("expected error without online units")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.StateOfChargePct != tc.soc || m.Partial != tc.partial {
+				t.Errorf("soc = %v, partial = %v, want %v, %v", m.StateOfChargePct, m.Partial, tc.soc, tc.partial)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package device
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/mluiten/evcc-homewizard-v2/internal/atomicfile"
+)
+
+// Capacity estimation parameters
+const (
+	minSocDelta         = 20.0 // % - Minimum SoC change of a segment before it is evaluated
+	maxReverseFraction  = 0.05 // Maximum share of energy flowing in the opposite direction within a segment
+	oneWayEfficiency    = 0.95 // AC to cell efficiency for a single direction (about 90% round-trip)
+	capacitySmoothing   = 0.2  // Weight of a new sample in the running estimate
+	minCapacityFraction = 0.5  // Samples below this fraction of nominal capacity are discarded
+	maxCapacityFraction = 1.2  // Samples above this fraction of nominal capacity are discarded
+	maxStateOfHealthPct = 100.0
+	capacityFileMode    = 0o600
+)
+
+// CapacityEstimate is the learned usable capacity of a battery
+type CapacityEstimate struct {
+	CapacitykWh      float64   `json:"capacity_kwh"`
+	StateOfHealthPct float64   `json:"state_of_health_pct"`
+	Samples          int       `json:"samples"` // Number of segments the estimate is based on
+	Cycles           int       `json:"cycles"`  // Cycle count at the last update
+	Updated          time.Time `json:"updated"`
+}
+
+// CapacityStore persists a learned capacity estimate
+type CapacityStore interface {
+	Load() (CapacityEstimate, error)
+	Save(CapacityEstimate) error
+}
+
+// FileCapacityStore persists a capacity estimate as JSON file
+type FileCapacityStore struct {
+	path string
+}
+
+// NewFileCapacityStore creates a capacity store backed by the given file
+func NewFileCapacityStore(path string) *FileCapacityStore {
+	return &FileCapacityStore{path: path}
+}
+
+// Load reads the estimate, returns os.ErrNotExist if nothing was learned yet
+func (s *FileCapacityStore) Load() (CapacityEstimate, error) {
+	var res CapacityEstimate
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return res, err
+	}
+
+	if err := json.Unmarshal(b, &res); err != nil {
+		return res, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	return res, nil
+}
+
+// Save atomically writes the estimate
+func (s *FileCapacityStore) Save(e CapacityEstimate) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(s.path, b, capacityFileMode)
+}
+
+// CapacityEstimator learns the usable capacity of a battery from SoC changes versus
+// energy throughput and derives the state of health from the nominal capacity
+type CapacityEstimator struct {
+	mu       sync.Mutex
+	nominal  float64
+	store    CapacityStore
+	estimate CapacityEstimate
+	anchor   *BatteryMeasurement // Start of the current segment
+}
+
+// NewCapacityEstimator creates an estimator for a battery with the given nominal capacity in kWh
+// A previously learned estimate is loaded from the store, which may be nil to disable persistence
+func NewCapacityEstimator(nominal float64, store CapacityStore) (*CapacityEstimator, error) {
+	if nominal <= 0 {
+		return nil, fmt.Errorf("invalid nominal capacity: %.2f", nominal)
+	}
+
+	e := &CapacityEstimator{
+		nominal: nominal,
+		store:   store,
+	}
+
+	if store != nil {
+		estimate, err := store.Load()
+		switch {
+		case err == nil:
+			e.estimate = estimate
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("loading capacity estimate: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// Observe feeds a battery measurement into the estimator
+func (e *CapacityEstimator) Observe(m BatteryMeasurement) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.anchor == nil {
+		e.anchor = &m
+		return nil
+	}
+
+	charged := m.EnergyImportkWh - e.anchor.EnergyImportkWh
+	discharged := m.EnergyExportkWh - e.anchor.EnergyExportkWh
+	socDelta := m.StateOfChargePct - e.anchor.StateOfChargePct
+
+	// Counter reset or SoC moving against the energy flow, start over
+	if charged < 0 || discharged < 0 || (socDelta > 0 && charged < discharged) || (socDelta < 0 && discharged < charged) {
+		e.anchor = &m
+		return nil
+	}
+
+	if math.Abs(socDelta) < minSocDelta {
+		return nil
+	}
+
+	// Only evaluate segments where energy flows predominantly in one direction
+	var energy float64
+	switch {
+	case socDelta > 0 && discharged <= maxReverseFraction*charged:
+		energy = (charged - discharged) * oneWayEfficiency
+	case socDelta < 0 && charged <= maxReverseFraction*discharged:
+		energy = (discharged - charged) / oneWayEfficiency
+	}
+
+	e.anchor = &m
+
+	if energy <= 0 {
+		return nil
+	}
+
+	sample := energy / (math.Abs(socDelta) / 100)
+	if sample < minCapacityFraction*e.nominal || sample > maxCapacityFraction*e.nominal {
+		return nil
+	}
+
+	if e.estimate.Samples == 0 {
+		e.estimate.CapacitykWh = sample
+	} else {
+		e.estimate.CapacitykWh += capacitySmoothing * (sample - e.estimate.CapacitykWh)
+	}
+
+	e.estimate.Samples++
+	e.estimate.Cycles = m.Cycles
+	e.estimate.StateOfHealthPct = min(maxStateOfHealthPct, 100*e.estimate.CapacitykWh/e.nominal)
+	e.estimate.Updated = time.Now()
+
+	if e.store == nil {
+		return nil
+	}
+
+	return e.store.Save(e.estimate)
+}
+
+// Capacity returns the learned usable capacity in kWh, or the nominal capacity if nothing was learned yet
+func (e *CapacityEstimator) Capacity() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.estimate.Samples == 0 {
+		return e.nominal
+	}
+	return e.estimate.CapacitykWh
+}
+
+// StateOfHealth returns the learned state of health in percent
+// Returns api.ErrNotAvailable if nothing was learned yet
+func (e *CapacityEstimator) StateOfHealth() (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.estimate.Samples == 0 {
+		return 0, api.ErrNotAvailable
+	}
+	return e.estimate.StateOfHealthPct, nil
+}
+
+// Estimate returns the current capacity estimate
+func (e *CapacityEstimator) Estimate() CapacityEstimate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.estimate
+}
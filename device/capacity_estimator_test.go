@@ -0,0 +1,111 @@
+package device
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+// batterySample is a SoC and energy counter reading fed to the estimator
+type batterySample struct {
+	soc, importkWh, exportkWh float64
+}
+
+func TestCapacityEstimator(t *testing.T) {
+	const nominal = 2.0
+
+	tests := []struct {
+		name     string
+		samples  []batterySample
+		capacity float64 // 0 = nothing learned
+	}{
+		{
+			name:     "charge segment",
+			samples:  []batterySample{{20, 0, 0}, {70, 1.0 / oneWayEfficiency, 0}},
+			capacity: 2.0,
+		},
+		{
+			name:     "discharge segment",
+			samples:  []batterySample{{80, 0, 0}, {30, 0, 1.0 * oneWayEfficiency}},
+			capacity: 2.0,
+		},
+		{
+			name:    "soc change too small",
+			samples: []batterySample{{20, 0, 0}, {35, 0.3, 0}},
+		},
+		{
+			name:    "mixed energy flow",
+			samples: []batterySample{{20, 0, 0}, {70, 1.5, 0.3}},
+		},
+		{
+			name:    "implausible capacity",
+			samples: []batterySample{{20, 0, 0}, {70, 0.2, 0}},
+		},
+		{
+			name:    "counter reset",
+			samples: []batterySample{{20, 5, 0}, {70, 1, 0}},
+		},
+		{
+			name:     "smoothing",
+			samples:  []batterySample{{20, 0, 0}, {70, 1.0 / oneWayEfficiency, 0}, {20, 1.0 / oneWayEfficiency, 0.8 * oneWayEfficiency}},
+			capacity: 2.0 + capacitySmoothing*(1.6-2.0),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewCapacityEstimator(nominal, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tc.samples {
+				if err := e.Observe(BatteryMeasurement{StateOfChargePct: s.soc, EnergyImportkWh: s.importkWh, EnergyExportkWh: s.exportkWh}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			soh, err := e.StateOfHealth()
+			if tc.capacity == 0 {
+				if e.Capacity() != nominal || !errors.Is(err, api.ErrNotAvailable) {
+					t.Errorf("capacity = %v, soh error = %v, want nominal and not available", e.Capacity(), err)
+				}
+				return
+			}
+
+			if math.Abs(e.Capacity()-tc.capacity) > 1e-9 {
+				t.Errorf("capacity = %v, want %v", e.Capacity(), tc.capacity)
+			}
+			if want := min(100, 100*tc.capacity/nominal); err != nil || math.Abs(soh-want) > 1e-9 {
+				t.Errorf("soh = %v, %v, want %v", soh, err, want)
+			}
+		})
+	}
+}
+
+func TestCapacityEstimatorStore(t *testing.T) {
+	store := NewFileCapacityStore(filepath.Join(t.TempDir(), "capacity.json"))
+
+	e, err := NewCapacityEstimator(2.0, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []batterySample{{20, 0, 0}, {60, 0.8 / oneWayEfficiency, 0}} {
+		if err := e.Observe(BatteryMeasurement{StateOfChargePct: s.soc, EnergyImportkWh: s.importkWh, EnergyExportkWh: s.exportkWh}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A new estimator continues with the learned estimate
+	e2, err := NewCapacityEstimator(2.0, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e2.Estimate(); math.Abs(got.CapacitykWh-2.0) > 1e-9 || got.Samples != 1 {
+		t.Errorf("loaded estimate = %+v, want 2 kWh from 1 sample", got)
+	}
+}
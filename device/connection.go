@@ -3,6 +3,7 @@ package device
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -21,6 +22,9 @@ const (
 	writeTimeout = 10 * time.Second
 )
 
+// ErrUnauthorized is returned when the device rejects the token
+var ErrUnauthorized = errors.New("unauthorized")
+
 // MessageHandler is called when a message is received
 type MessageHandler func(msgType string, data json.RawMessage) error
 
@@ -178,12 +182,20 @@ func (c *Connection) authenticate() error {
 	}
 
 	// Wait for authorization confirmation
-	var authConfirm AuthConfirm
+	var authConfirm Message
 	if err := c.readMessage(ctx, &authConfirm); err != nil {
 		return fmt.Errorf("waiting for auth confirm: %w", err)
 	}
 
-	if authConfirm.Type != "authorized" {
+	switch authConfirm.Type {
+	case "authorized":
+	case "error":
+		// Token rejected by the device
+		var errMsg ErrorMessage
+		errMsg.Data.Message = string(authConfirm.Data)
+		_ = json.Unmarshal(authConfirm.Data, &errMsg.Data)
+		return fmt.Errorf("%w: %s", ErrUnauthorized, errMsg.Data.Message)
+	default:
 		return fmt.Errorf("unexpected message type: %s, expected: authorized", authConfirm.Type)
 	}
 
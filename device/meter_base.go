@@ -38,11 +38,41 @@ type MeterMeasurement interface {
 type P1Measurement struct {
 	CommonMeasurement
 
+	// Energy measurements - totals over all tariffs, nil when not reported by older firmware
+	EnergyImportkWh *float64 `json:"energy_import_kwh,omitempty"`
+	EnergyExportkWh *float64 `json:"energy_export_kwh,omitempty"`
+
 	// Energy measurements - tariff breakdown (P1 meters)
 	EnergyImportT1kWh float64 `json:"energy_import_t1_kwh"`
 	EnergyImportT2kWh float64 `json:"energy_import_t2_kwh"`
+	EnergyImportT3kWh float64 `json:"energy_import_t3_kwh"` // Only on meters with more than 2 tariffs
+	EnergyImportT4kWh float64 `json:"energy_import_t4_kwh"` // Only on meters with more than 2 tariffs
 	EnergyExportT1kWh float64 `json:"energy_export_t1_kwh"`
 	EnergyExportT2kWh float64 `json:"energy_export_t2_kwh"`
+	EnergyExportT3kWh float64 `json:"energy_export_t3_kwh"` // Only on meters with more than 2 tariffs
+	EnergyExportT4kWh float64 `json:"energy_export_t4_kwh"` // Only on meters with more than 2 tariffs
+
+	// Meter information - nil when not reported by the smart meter
+	ProtocolVersion *int `json:"protocol_version,omitempty"` // DSMR version, e.g. 50 for 5.0
+	Tariff          *int `json:"tariff,omitempty"`           // Active tariff (1-4)
+
+	// Grid frequency - nil when not reported (DSMR < 5.0)
+	FrequencyHz *float64 `json:"frequency_hz,omitempty"`
+
+	// Power quality counters - nil when not reported by the smart meter
+	AnyPowerFailCount   *int `json:"any_power_fail_count,omitempty"`
+	LongPowerFailCount  *int `json:"long_power_fail_count,omitempty"`
+	VoltageSagL1Count   *int `json:"voltage_sag_l1_count,omitempty"`
+	VoltageSagL2Count   *int `json:"voltage_sag_l2_count,omitempty"` // 3-phase only
+	VoltageSagL3Count   *int `json:"voltage_sag_l3_count,omitempty"` // 3-phase only
+	VoltageSwellL1Count *int `json:"voltage_swell_l1_count,omitempty"`
+	VoltageSwellL2Count *int `json:"voltage_swell_l2_count,omitempty"` // 3-phase only
+	VoltageSwellL3Count *int `json:"voltage_swell_l3_count,omitempty"` // 3-phase only
+
+	// Peak measurements - only reported by Belgian meters (capacity tariff)
+	AveragePower15mW          *float64 `json:"average_power_15m_w,omitempty"`
+	MonthlyPowerPeakW         *float64 `json:"monthly_power_peak_w,omitempty"`
+	MonthlyPowerPeakTimestamp *string  `json:"monthly_power_peak_timestamp,omitempty"` // Local time, e.g. "2024-06-04T10:11:22"
 }
 
 func (m P1Measurement) GetCommon() CommonMeasurement { return m.CommonMeasurement }
@@ -54,6 +84,27 @@ type KWHMeasurement struct {
 	// Energy measurements - simple totals (kWh meters)
 	EnergyImportkWh float64 `json:"energy_import_kwh"`
 	EnergyExportkWh float64 `json:"energy_export_kwh"`
+
+	// Apparent power measurements - nil when not reported by the meter
+	ApparentPowerVA   *float64 `json:"apparent_power_va,omitempty"`
+	ApparentPowerL1VA *float64 `json:"apparent_power_l1_va,omitempty"` // 3-phase only
+	ApparentPowerL2VA *float64 `json:"apparent_power_l2_va,omitempty"` // 3-phase only
+	ApparentPowerL3VA *float64 `json:"apparent_power_l3_va,omitempty"` // 3-phase only
+
+	// Reactive power measurements - nil when not reported by the meter
+	ReactivePowerVAR   *float64 `json:"reactive_power_var,omitempty"`
+	ReactivePowerL1VAR *float64 `json:"reactive_power_l1_var,omitempty"` // 3-phase only
+	ReactivePowerL2VAR *float64 `json:"reactive_power_l2_var,omitempty"` // 3-phase only
+	ReactivePowerL3VAR *float64 `json:"reactive_power_l3_var,omitempty"` // 3-phase only
+
+	// Power factor measurements - nil when not reported, e.g. no total on HWE-KWH3
+	PowerFactor   *float64 `json:"power_factor,omitempty"`
+	PowerFactorL1 *float64 `json:"power_factor_l1,omitempty"` // 3-phase only
+	PowerFactorL2 *float64 `json:"power_factor_l2,omitempty"` // 3-phase only
+	PowerFactorL3 *float64 `json:"power_factor_l3,omitempty"` // 3-phase only
+
+	// Grid frequency - nil when not reported by the meter
+	FrequencyHz *float64 `json:"frequency_hz,omitempty"`
 }
 
 func (m KWHMeasurement) GetCommon() CommonMeasurement { return m.CommonMeasurement }
@@ -79,8 +130,8 @@ func (d *baseMeterDevice[T]) GetMeasurement() (T, error) {
 func (d *baseMeterDevice[T]) handleMessage(msgType string, data json.RawMessage) error {
 	switch msgType {
 	case "measurement":
-		var m T
-		if err := json.Unmarshal(data, &m); err != nil {
+		m, err := decodeMeasurement[T](d.decode, data)
+		if err != nil {
 			return fmt.Errorf("unmarshal meter measurement: %w", err)
 		}
 		d.measurement.Set(m)
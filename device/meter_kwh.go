@@ -3,9 +3,28 @@ package device
 import (
 	"time"
 
+	"github.com/evcc-io/evcc/api"
 	"github.com/evcc-io/evcc/util"
 )
 
+// kwhTopics are the WebSocket topics of kWh meters
+var kwhTopics = []string{"measurement"}
+
+func init() {
+	for _, productType := range []string{"HWE-KWH1", "HWE-KWH3"} {
+		RegisterProduct(Product{
+			ProductType: productType,
+			DeviceType:  DeviceTypeKWHMeter,
+			Usage:       "pv",
+			Topics:      kwhTopics,
+			Decode:      decodeAs[KWHMeasurement](),
+			New: func(host, token string, timeout time.Duration) Device {
+				return NewKWHMeterDevice(host, token, timeout)
+			},
+		})
+	}
+}
+
 // KWHMeterDevice represents a kWh meter (HWE-KWH1, HWE-KWH3)
 type KWHMeterDevice struct {
 	*baseMeterDevice[KWHMeasurement]
@@ -15,13 +34,13 @@ type KWHMeterDevice struct {
 func NewKWHMeterDevice(host, token string, timeout time.Duration) *KWHMeterDevice {
 	d := &KWHMeterDevice{
 		baseMeterDevice: &baseMeterDevice[KWHMeasurement]{
-			deviceBase:  newDeviceBase(DeviceTypeKWHMeter, host, token, timeout),
+			deviceBase:  newDeviceBase(DeviceTypeKWHMeter, decodeAs[KWHMeasurement](), host, token, timeout),
 			measurement: util.NewMonitor[KWHMeasurement](timeout),
 		},
 	}
 
 	// Create connection with message handler
-	d.conn = NewConnection(host, token, d.handleMessage)
+	d.conn = NewConnection(host, token, d.handleMessage, kwhTopics...)
 
 	return d
 }
@@ -40,3 +59,100 @@ func (d *KWHMeterDevice) GetTotalEnergy(usePVExport bool) (float64, error) {
 	}
 	return m.EnergyImportkWh, nil
 }
+
+// GetApparentPower returns the total apparent power in VA
+func (d *KWHMeterDevice) GetApparentPower() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.ApparentPowerVA)
+}
+
+// GetReactivePower returns the total reactive power in var
+func (d *KWHMeterDevice) GetReactivePower() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.ReactivePowerVAR)
+}
+
+// GetPowerFactor returns the total power factor (0-1)
+// Returns api.ErrNotAvailable if not reported, e.g. by HWE-KWH3 which only reports per-phase power factors
+func (d *KWHMeterDevice) GetPowerFactor() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.PowerFactor)
+}
+
+// GetFrequency returns the grid frequency in Hz
+func (d *KWHMeterDevice) GetFrequency() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.FrequencyHz)
+}
+
+// GetPhaseApparentPowers returns the per-phase apparent powers in VA
+// For 1-phase: returns (total, 0, 0)
+// For 3-phase: returns (L1, L2, L3)
+// Returns api.ErrNotAvailable if not reported by the meter
+func (d *KWHMeterDevice) GetPhaseApparentPowers(phases int) (float64, float64, float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if phases == 1 {
+		v, err := reported(m.ApparentPowerVA)
+		return v, 0, 0, err
+	}
+	return phaseValues(m.ApparentPowerL1VA, m.ApparentPowerL2VA, m.ApparentPowerL3VA)
+}
+
+// GetPhaseReactivePowers returns the per-phase reactive powers in var
+// For 1-phase: returns (total, 0, 0)
+// For 3-phase: returns (L1, L2, L3)
+// Returns api.ErrNotAvailable if not reported by the meter
+func (d *KWHMeterDevice) GetPhaseReactivePowers(phases int) (float64, float64, float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if phases == 1 {
+		v, err := reported(m.ReactivePowerVAR)
+		return v, 0, 0, err
+	}
+	return phaseValues(m.ReactivePowerL1VAR, m.ReactivePowerL2VAR, m.ReactivePowerL3VAR)
+}
+
+// GetPhasePowerFactors returns the per-phase power factors
+// For 1-phase: returns (total, 0, 0)
+// For 3-phase: returns (L1, L2, L3)
+// Returns api.ErrNotAvailable if not reported by the meter
+func (d *KWHMeterDevice) GetPhasePowerFactors(phases int) (float64, float64, float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if phases == 1 {
+		v, err := reported(m.PowerFactor)
+		return v, 0, 0, err
+	}
+	return phaseValues(m.PowerFactorL1, m.PowerFactorL2, m.PowerFactorL3)
+}
+
+// phaseValues dereferences per-phase measurement values
+// Returns api.ErrNotAvailable if any phase was not reported
+func phaseValues(l1, l2, l3 *float64) (float64, float64, float64, error) {
+	if l1 == nil || l2 == nil || l3 == nil {
+		return 0, 0, 0, api.ErrNotAvailable
+	}
+	return *l1, *l2, *l3, nil
+}
@@ -0,0 +1,58 @@
+package device
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+func TestKWHMeasurementNotReported(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		phases  int
+		pf      float64
+		pfErr   error
+		phaseL2 float64
+		phErr   error
+	}{
+		{
+			name:   "1-phase",
+			data:   `{"power_w": 100, "power_factor": 0.9, "apparent_power_va": 110}`,
+			phases: 1, pf: 0.9,
+		},
+		{
+			name:   "3-phase without total power factor",
+			data:   `{"power_w": 100, "power_factor_l1": 0.9, "power_factor_l2": 0.8, "power_factor_l3": 0.7}`,
+			phases: 3, pfErr: api.ErrNotAvailable, phaseL2: 0.8,
+		},
+		{
+			name:   "3-phase missing a phase",
+			data:   `{"power_w": 100, "power_factor_l1": 0.9}`,
+			phases: 3, pfErr: api.ErrNotAvailable, phErr: api.ErrNotAvailable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var m KWHMeasurement
+			if err := json.Unmarshal([]byte(tc.data), &m); err != nil {
+				t.Fatal(err)
+			}
+
+			pf, err := reported(m.PowerFactor)
+			if !errors.Is(err, tc.pfErr) || pf != tc.pf {
+				t.Errorf("power factor = %v, %v, want %v, %v", pf, err, tc.pf, tc.pfErr)
+			}
+
+			if tc.phases == 3 {
+				_, l2, _, err := phaseValues(m.PowerFactorL1, m.PowerFactorL2, m.PowerFactorL3)
+				if !errors.Is(err, tc.phErr) || l2 != tc.phaseL2 {
+					t.Errorf("L2 power factor = %v, %v, want %v, %v", l2, err, tc.phaseL2, tc.phErr)
+				}
+			}
+		})
+	}
+}
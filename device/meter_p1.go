@@ -1,6 +1,7 @@
 package device
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,24 +12,51 @@ import (
 	"github.com/evcc-io/evcc/util/request"
 )
 
+// TariffMapping defines which P1 tariff register holds the low and normal tariff
+// Dutch and Belgian meters label the T1/T2 registers oppositely
+type TariffMapping string
+
+const (
+	TariffMappingNL TariffMapping = "nl" // T1 = low (dal), T2 = normal (piek)
+	TariffMappingBE TariffMapping = "be" // T1 = normal (dag), T2 = low (nacht)
+)
+
+// p1Topics are the WebSocket topics of P1 meters
+var p1Topics = []string{"measurement", "batteries"}
+
+func init() {
+	RegisterProduct(Product{
+		ProductType: "HWE-P1",
+		DeviceType:  DeviceTypeP1Meter,
+		Usage:       "grid",
+		Topics:      p1Topics,
+		Decode:      decodeAs[P1Measurement](),
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewP1MeterDevice(host, token, timeout)
+		},
+	})
+}
+
 // P1MeterDevice represents a P1 meter (HWE-P1) with battery control
 type P1MeterDevice struct {
 	*baseMeterDevice[P1Measurement]
-	batteriesData *util.Monitor[BatteriesData]
+	batteriesData *util.Monitor[BatteriesState]
+	tariffMapping TariffMapping
 }
 
 // NewP1MeterDevice creates a new P1 meter device instance
 func NewP1MeterDevice(host, token string, timeout time.Duration) *P1MeterDevice {
 	d := &P1MeterDevice{
 		baseMeterDevice: &baseMeterDevice[P1Measurement]{
-			deviceBase:  newDeviceBase(DeviceTypeP1Meter, host, token, timeout),
+			deviceBase:  newDeviceBase(DeviceTypeP1Meter, decodeAs[P1Measurement](), host, token, timeout),
 			measurement: util.NewMonitor[P1Measurement](timeout),
 		},
-		batteriesData: util.NewMonitor[BatteriesData](timeout),
+		batteriesData: util.NewMonitor[BatteriesState](timeout),
+		tariffMapping: TariffMappingNL,
 	}
 
 	// Create connection with message handler, subscribe to measurement and batteries topics
-	d.conn = NewConnection(host, token, d.handleP1Message, "measurement", "batteries")
+	d.conn = NewConnection(host, token, d.handleP1Message, p1Topics...)
 
 	return d
 }
@@ -37,7 +65,7 @@ func NewP1MeterDevice(host, token string, timeout time.Duration) *P1MeterDevice
 func (d *P1MeterDevice) handleP1Message(msgType string, data json.RawMessage) error {
 	switch msgType {
 	case "batteries":
-		var b BatteriesData
+		var b BatteriesState
 		if err := json.Unmarshal(data, &b); err != nil {
 			return fmt.Errorf("unmarshal batteries data: %w", err)
 		}
@@ -75,38 +103,317 @@ func (d *P1MeterDevice) GetPhasePowers(phases int) (float64, float64, float64, e
 	return common.PowerL1W, common.PowerL2W, common.PowerL3W, nil
 }
 
-// GetTotalEnergy returns the total import energy for P1 meters (sum of T1 and T2 tariffs)
+// GetTotalEnergy returns the total import energy for P1 meters (sum of all tariffs)
 // P1 meters are always grid meters, so we always return import energy
 func (d *P1MeterDevice) GetTotalEnergy() (float64, error) {
+	return d.GetImportEnergy()
+}
+
+// SetTariffMapping configures which tariff register is low and which is normal
+// Should be called before Start, defaults to TariffMappingNL
+func (d *P1MeterDevice) SetTariffMapping(mapping TariffMapping) error {
+	switch mapping {
+	case TariffMappingNL, TariffMappingBE:
+		d.tariffMapping = mapping
+		return nil
+	default:
+		return fmt.Errorf("invalid tariff mapping: %s", mapping)
+	}
+}
+
+// GetImportEnergy returns the total imported energy over all tariffs in kWh
+func (d *P1MeterDevice) GetImportEnergy() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+
+	// Older firmware does not report the total, fall back to the tariff sum
+	if m.EnergyImportkWh == nil {
+		return m.EnergyImportT1kWh + m.EnergyImportT2kWh + m.EnergyImportT3kWh + m.EnergyImportT4kWh, nil
+	}
+	return *m.EnergyImportkWh, nil
+}
+
+// GetExportEnergy returns the total exported energy over all tariffs in kWh
+func (d *P1MeterDevice) GetExportEnergy() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+
+	// Older firmware does not report the total, fall back to the tariff sum
+	if m.EnergyExportkWh == nil {
+		return m.EnergyExportT1kWh + m.EnergyExportT2kWh + m.EnergyExportT3kWh + m.EnergyExportT4kWh, nil
+	}
+	return *m.EnergyExportkWh, nil
+}
+
+// GetNetEnergy returns imported minus exported energy in kWh
+// Negative values indicate net feed-in
+func (d *P1MeterDevice) GetNetEnergy() (float64, error) {
+	imported, err := d.GetImportEnergy()
+	if err != nil {
+		return 0, err
+	}
+
+	exported, err := d.GetExportEnergy()
+	if err != nil {
+		return 0, err
+	}
+
+	return imported - exported, nil
+}
+
+// GetTariffEnergy returns the import and export energy of a tariff register (1-4) in kWh
+func (d *P1MeterDevice) GetTariffEnergy(tariff int) (float64, float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch tariff {
+	case 1:
+		return m.EnergyImportT1kWh, m.EnergyExportT1kWh, nil
+	case 2:
+		return m.EnergyImportT2kWh, m.EnergyExportT2kWh, nil
+	case 3:
+		return m.EnergyImportT3kWh, m.EnergyExportT3kWh, nil
+	case 4:
+		return m.EnergyImportT4kWh, m.EnergyExportT4kWh, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid tariff: %d", tariff)
+	}
+}
+
+// GetLowTariffEnergy returns the import and export energy of the low tariff in kWh
+func (d *P1MeterDevice) GetLowTariffEnergy() (float64, float64, error) {
+	return d.GetTariffEnergy(d.lowTariff())
+}
+
+// GetNormalTariffEnergy returns the import and export energy of the normal tariff in kWh
+func (d *P1MeterDevice) GetNormalTariffEnergy() (float64, float64, error) {
+	return d.GetTariffEnergy(3 - d.lowTariff())
+}
+
+// IsLowTariff returns true if the low tariff is currently active
+func (d *P1MeterDevice) IsLowTariff() (bool, error) {
+	tariff, err := d.GetTariff()
+	if err != nil {
+		return false, err
+	}
+	return tariff == d.lowTariff(), nil
+}
+
+// lowTariff returns the tariff register holding the low tariff
+func (d *P1MeterDevice) lowTariff() int {
+	if d.tariffMapping == TariffMappingBE {
+		return 2
+	}
+	return 1
+}
+
+// p1TimestampLayout is the local-time layout used by P1 meters for timestamps
+const p1TimestampLayout = "2006-01-02T15:04:05"
+
+// reported dereferences an optional measurement value
+// Returns api.ErrNotAvailable if the value was not reported by the meter
+func reported[T any](v *T) (T, error) {
+	if v == nil {
+		var zero T
+		return zero, api.ErrNotAvailable
+	}
+	return *v, nil
+}
+
+// GetProtocolVersion returns the DSMR protocol version reported by the smart meter (e.g. 50 for 5.0)
+func (d *P1MeterDevice) GetProtocolVersion() (int, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.ProtocolVersion)
+}
+
+// GetTariff returns the currently active tariff (1-4)
+func (d *P1MeterDevice) GetTariff() (int, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.Tariff)
+}
+
+// GetFrequency returns the grid frequency in Hz
+func (d *P1MeterDevice) GetFrequency() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return reported(m.FrequencyHz)
+}
+
+// GetPowerFailCounts returns the number of power failures and long power failures
+func (d *P1MeterDevice) GetPowerFailCounts() (int, int, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	anyCount, err := reported(m.AnyPowerFailCount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	longCount, err := reported(m.LongPowerFailCount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return anyCount, longCount, nil
+}
+
+// GetVoltageSagCounts returns the per-phase voltage sag counts
+// For 1-phase meters L2 and L3 are returned as 0
+func (d *P1MeterDevice) GetVoltageSagCounts() (int, int, int, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return phaseCounts(m.VoltageSagL1Count, m.VoltageSagL2Count, m.VoltageSagL3Count)
+}
+
+// GetVoltageSwellCounts returns the per-phase voltage swell counts
+// For 1-phase meters L2 and L3 are returned as 0
+func (d *P1MeterDevice) GetVoltageSwellCounts() (int, int, int, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return phaseCounts(m.VoltageSwellL1Count, m.VoltageSwellL2Count, m.VoltageSwellL3Count)
+}
+
+// phaseCounts dereferences per-phase counters, treating missing L2/L3 as 0
+// Returns api.ErrNotAvailable if L1 was not reported
+func phaseCounts(l1, l2, l3 *int) (int, int, int, error) {
+	c1, err := reported(l1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var c2, c3 int
+	if l2 != nil {
+		c2 = *l2
+	}
+	if l3 != nil {
+		c3 = *l3
+	}
+
+	return c1, c2, c3, nil
+}
+
+// GetAveragePower15m returns the average power of the current quarter-hour in W (Belgian meters only)
+func (d *P1MeterDevice) GetAveragePower15m() (float64, error) {
 	m, err := d.GetMeasurement()
 	if err != nil {
 		return 0, err
 	}
-	return m.EnergyImportT1kWh + m.EnergyImportT2kWh, nil
+	return reported(m.AveragePower15mW)
+}
+
+// GetMonthlyPowerPeak returns the highest quarter-hour average power of this month in W
+// and the time at which it occurred (Belgian meters only)
+func (d *P1MeterDevice) GetMonthlyPowerPeak() (float64, time.Time, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	peak, err := reported(m.MonthlyPowerPeakW)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	// The timestamp is optional, a missing or malformed value is reported as zero time
+	var ts time.Time
+	if m.MonthlyPowerPeakTimestamp != nil {
+		if t, err := time.ParseInLocation(p1TimestampLayout, *m.MonthlyPowerPeakTimestamp, time.Local); err == nil {
+			ts = t
+		} else {
+			d.log.DEBUG.Printf("invalid monthly power peak timestamp %q: %v", *m.MonthlyPowerPeakTimestamp, err)
+		}
+	}
+
+	return peak, ts, nil
+}
+
+// GetBatteries returns the latest battery system state
+func (d *P1MeterDevice) GetBatteries() (BatteriesState, error) {
+	b, err := d.batteriesData.Get()
+	if err != nil {
+		return BatteriesState{}, api.ErrTimeout
+	}
+	return b, nil
 }
 
 // GetBatteryPowerLimits returns the battery power limits (charge, discharge in W)
 func (d *P1MeterDevice) GetBatteryPowerLimits() (float64, float64, error) {
-	b, err := d.batteriesData.Get()
+	b, err := d.GetBatteries()
 	if err != nil {
-		return 0, 0, api.ErrTimeout
+		return 0, 0, err
 	}
 	return b.MaxConsumptionW, b.MaxProductionW, nil
 }
 
+// batteriesRequest is the payload for changing the battery system state
+type batteriesRequest struct {
+	Mode        BatteryMode          `json:"mode,omitempty"`
+	Permissions *[]BatteryPermission `json:"permissions,omitempty"` // Pointer, so an empty list is still sent
+}
+
 // SetBatteryMode sets the battery control mode via P1 meter
-func (d *P1MeterDevice) SetBatteryMode(mode string) error {
+func (d *P1MeterDevice) SetBatteryMode(mode BatteryMode) error {
+	if !mode.Valid() {
+		return fmt.Errorf("invalid battery mode: %s", mode)
+	}
+
 	d.log.INFO.Printf("setting battery mode to: %s", mode)
 
+	return d.setBatteries(batteriesRequest{Mode: mode})
+}
+
+// SetBatteryPermissions sets what the batteries may do in the current mode
+// An empty list prevents both charging and discharging
+func (d *P1MeterDevice) SetBatteryPermissions(permissions ...BatteryPermission) error {
+	for _, p := range permissions {
+		if !p.Valid() {
+			return fmt.Errorf("invalid battery permission: %s", p)
+		}
+	}
+
+	if permissions == nil {
+		permissions = []BatteryPermission{}
+	}
+
+	d.log.INFO.Printf("setting battery permissions to: %v", permissions)
+
+	return d.setBatteries(batteriesRequest{Permissions: &permissions})
+}
+
+// setBatteries sends a battery control request via WebSocket, falling back to HTTP
+func (d *P1MeterDevice) setBatteries(reqBody batteriesRequest) error {
 	// Try WebSocket control first
-	wsMsg := map[string]any{
-		"type": "batteries",
-		"data": map[string]string{"mode": mode},
+	wsMsg := struct {
+		Type string           `json:"type"`
+		Data batteriesRequest `json:"data"`
+	}{
+		Type: "batteries",
+		Data: reqBody,
 	}
 
 	if err := d.conn.Send(wsMsg); err != nil {
 		d.log.DEBUG.Printf("WebSocket battery control failed, falling back to HTTP: %v", err)
-		return d.setBatteryModeHTTP(mode)
+		return d.setBatteriesHTTP(reqBody)
 	}
 
 	// Give the device a moment to process
@@ -115,35 +422,25 @@ func (d *P1MeterDevice) SetBatteryMode(mode string) error {
 	return nil
 }
 
-// setBatteryModeHTTP sets battery mode via HTTP PUT
-func (d *P1MeterDevice) setBatteryModeHTTP(mode string) error {
+// setBatteriesHTTP sets the battery system state via HTTP PUT
+func (d *P1MeterDevice) setBatteriesHTTP(reqBody batteriesRequest) error {
 	uri := fmt.Sprintf("https://%s/api/batteries", d.host)
-	d.log.INFO.Printf("sending HTTP PUT to %s with mode: %s", uri, mode)
+	d.log.INFO.Printf("sending HTTP PUT to %s", uri)
 
-	reqBody := struct {
-		Mode string `json:"mode"`
-	}{
-		Mode: mode,
-	}
-
-	req, err := request.New(http.MethodPut, uri, request.MarshalJSON(reqBody), request.JSONEncoding)
+	req, err := NewAPIRequest(context.Background(), http.MethodPut, uri, d.token, request.MarshalJSON(reqBody))
 	if err != nil {
 		d.log.ERROR.Printf("failed to create HTTP request: %v", err)
 		return err
 	}
 
-	// Set required headers for HomeWizard API v2
-	req.Header.Set("Authorization", "Bearer "+d.token)
-	req.Header.Set("X-Api-Version", "2")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	var res BatteriesData
+	var res BatteriesState
 	if err := d.DoJSON(req, &res); err != nil {
 		d.log.ERROR.Printf("HTTP request failed: %v", err)
 		return err
 	}
 
-	d.log.INFO.Printf("battery mode set successfully via HTTP: %s (response: mode=%s, power=%.1fW)", mode, res.Mode, res.PowerW)
+	d.batteriesData.Set(res)
+
+	d.log.INFO.Printf("battery state set successfully via HTTP (response: mode=%s, permissions=%v, power=%.1fW)", res.Mode, res.Permissions, res.PowerW)
 	return nil
 }
@@ -0,0 +1,51 @@
+package device
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestP1EnergyTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		imported float64
+		exported float64
+	}{
+		{
+			name:     "totals reported",
+			data:     `{"energy_import_kwh": 30, "energy_export_kwh": 7, "energy_import_t1_kwh": 10, "energy_import_t2_kwh": 20}`,
+			imported: 30, exported: 7,
+		},
+		{
+			name:     "totals not reported",
+			data:     `{"energy_import_t1_kwh": 10, "energy_import_t2_kwh": 20, "energy_export_t1_kwh": 3, "energy_export_t2_kwh": 4}`,
+			imported: 30, exported: 7,
+		},
+		{
+			name:     "zero totals",
+			data:     `{"energy_import_kwh": 0, "energy_export_kwh": 0, "energy_import_t1_kwh": 10, "energy_export_t2_kwh": 4}`,
+			imported: 0, exported: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewP1MeterDevice("192.0.2.1", "token", time.Minute)
+			if err := d.handleP1Message("measurement", json.RawMessage(tc.data)); err != nil {
+				t.Fatal(err)
+			}
+
+			imported, err := d.GetImportEnergy()
+			if err != nil || imported != tc.imported {
+				t.Errorf("import = %v, %v, want %v", imported, err, tc.imported)
+			}
+
+			exported, err := d.GetExportEnergy()
+			if err != nil || exported != tc.exported {
+				t.Errorf("export = %v, %v, want %v", exported, err, tc.exported)
+			}
+		})
+	}
+}
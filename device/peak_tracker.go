@@ -0,0 +1,245 @@
+package device
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/evcc/api"
+)
+
+// Capacity tariff defaults (Belgian capaciteitstarief)
+const (
+	QuarterHour         = 15 * time.Minute
+	DefaultPeakInterval = 10 * time.Second
+	DefaultMinimumPeakW = 2500.0 // W - Minimum billed monthly peak in Belgium
+)
+
+// PeakTrackerConfig configures the capacity tariff peak tracker
+type PeakTrackerConfig struct {
+	CapW     float64       // Optional hard cap for the quarter-hour average in W, 0 = month's peak only
+	MarginW  float64       // Fire OnExceed when the projection comes within this margin of the threshold
+	MinimumW float64       // Peaks below this value are free, defaults to DefaultMinimumPeakW
+	Interval time.Duration // Sampling interval for live power, defaults to DefaultPeakInterval
+
+	// OnExceed is called when the projected quarter-hour average is about to exceed the threshold
+	OnExceed func(PeakProjection)
+	// OnClear is called when the projection drops back below the threshold after OnExceed
+	OnClear func(PeakProjection)
+}
+
+// PeakProjection describes the state of the current quarter-hour
+type PeakProjection struct {
+	QuarterStart time.Time // Start of the current quarter-hour
+	Elapsed      time.Duration
+	PowerW       float64 // Latest live grid power
+	AverageW     float64 // Running average since the start of the quarter-hour
+	ProjectedW   float64 // Projected average at the end of the quarter-hour at current power
+	MonthPeakW   float64 // Highest quarter-hour average of this month
+	ThresholdW   float64 // Effective threshold (min of month's peak and cap, not below minimum)
+	HeadroomW    float64 // Additional power that may be drawn for the rest of the quarter-hour
+	Exceeding    bool    // Projection is within margin of or above the threshold
+}
+
+// peakSource provides the P1 values required by the peak tracker
+type peakSource interface {
+	GetPower() (float64, error)
+	GetAveragePower15m() (float64, error)
+	GetMonthlyPowerPeak() (float64, time.Time, error)
+}
+
+// PeakTracker tracks the running quarter-hour average power of a P1 meter and
+// warns before a new monthly capacity tariff peak is set
+type PeakTracker struct {
+	mu  sync.Mutex
+	src peakSource
+	cfg PeakTrackerConfig
+
+	quarterStart time.Time // Start of the quarter-hour being integrated
+	trackedFrom  time.Time // Start of continuous sampling in the current quarter-hour
+	lastSample   time.Time
+	lastPowerW   float64
+	energyWs     float64 // Integrated energy since trackedFrom in Ws
+	monthPeakW   float64 // Highest completed quarter-hour average observed this month
+	month        time.Month
+
+	projection PeakProjection
+	valid      bool
+}
+
+// NewPeakTracker creates a capacity tariff peak tracker on top of a P1 meter
+func NewPeakTracker(p1 *P1MeterDevice, cfg PeakTrackerConfig) *PeakTracker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPeakInterval
+	}
+	if cfg.MinimumW <= 0 {
+		cfg.MinimumW = DefaultMinimumPeakW
+	}
+	if cfg.MarginW < 0 {
+		cfg.MarginW = 0
+	}
+
+	return &PeakTracker{
+		src: p1,
+		cfg: cfg,
+	}
+}
+
+// Run samples the P1 meter until the context is cancelled
+func (t *PeakTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	t.sample(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.sample(now)
+		}
+	}
+}
+
+// Projection returns the latest quarter-hour projection
+func (t *PeakTracker) Projection() (PeakProjection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.valid {
+		return PeakProjection{}, api.ErrTimeout
+	}
+	return t.projection, nil
+}
+
+// sample takes a live power reading and updates the projection
+func (t *PeakTracker) sample(now time.Time) {
+	power, err := t.src.GetPower()
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+
+	quarterStart := quarterStartOf(now)
+
+	// Month rollover resets the observed peak
+	if now.Month() != t.month {
+		t.month = now.Month()
+		t.monthPeakW = 0
+	}
+
+	// Samples further apart than this are a gap, e.g. after an outage of the meter
+	continuous := !t.lastSample.IsZero() && now.Sub(t.lastSample) <= 2*t.cfg.Interval
+
+	// Quarter rollover finalizes the previous quarter-hour
+	if !quarterStart.Equal(t.quarterStart) {
+		if !t.quarterStart.IsZero() {
+			// Integrate up to the end of the previous quarter-hour only
+			quarterEnd := t.quarterStart.Add(QuarterHour)
+			t.energyWs += t.lastPowerW * quarterEnd.Sub(t.lastSample).Seconds()
+
+			// Only a continuously observed quarter-hour counts as a peak
+			if !t.trackedFrom.After(t.quarterStart) && quarterEnd.Sub(t.lastSample) <= 2*t.cfg.Interval &&
+				t.quarterStart.Month() == now.Month() {
+				t.monthPeakW = max(t.monthPeakW, t.energyWs/QuarterHour.Seconds())
+			}
+		}
+
+		t.quarterStart = quarterStart
+		t.trackedFrom = now
+		t.energyWs = 0
+		if continuous {
+			// The new quarter-hour is fully covered
+			t.trackedFrom = quarterStart
+			t.energyWs = t.lastPowerW * now.Sub(quarterStart).Seconds()
+		}
+	} else if continuous {
+		t.energyWs += t.lastPowerW * now.Sub(t.lastSample).Seconds()
+	} else {
+		// Gap within the quarter-hour, continue as if tracking started now
+		t.trackedFrom = now
+		t.energyWs = 0
+	}
+
+	t.lastSample = now
+	t.lastPowerW = power
+
+	p := t.project(now, power)
+	wasExceeding := t.valid && t.projection.Exceeding
+	t.projection = p
+	t.valid = true
+
+	t.mu.Unlock()
+
+	// Callbacks are edge-triggered and run outside the lock
+	switch {
+	case p.Exceeding && !wasExceeding && t.cfg.OnExceed != nil:
+		t.cfg.OnExceed(p)
+	case !p.Exceeding && wasExceeding && t.cfg.OnClear != nil:
+		t.cfg.OnClear(p)
+	}
+}
+
+// project computes the end-of-quarter projection, caller must hold the lock
+func (t *PeakTracker) project(now time.Time, power float64) PeakProjection {
+	elapsed := now.Sub(t.quarterStart)
+	remaining := QuarterHour - elapsed
+
+	var average float64
+	if tracked := now.Sub(t.trackedFrom); !t.trackedFrom.After(t.quarterStart) && elapsed > 0 {
+		average = t.energyWs / elapsed.Seconds()
+	} else if meterAvg, err := t.src.GetAveragePower15m(); err == nil {
+		// Started mid-quarter, rely on the meter's running average
+		average = meterAvg
+	} else if tracked > 0 {
+		// No meter average either, extrapolate from what was observed
+		average = t.energyWs / tracked.Seconds()
+	} else {
+		average = power
+	}
+
+	projected := (average*elapsed.Seconds() + power*remaining.Seconds()) / QuarterHour.Seconds()
+
+	monthPeak := t.monthPeakW
+	if meterPeak, _, err := t.src.GetMonthlyPowerPeak(); err == nil {
+		monthPeak = max(monthPeak, meterPeak)
+	}
+
+	var threshold float64
+	switch {
+	case monthPeak > 0 && t.cfg.CapW > 0:
+		threshold = min(monthPeak, t.cfg.CapW)
+	case monthPeak > 0:
+		threshold = monthPeak
+	default:
+		threshold = t.cfg.CapW
+	}
+
+	// Peaks below the minimum are free
+	threshold = max(threshold, t.cfg.MinimumW)
+
+	// Power that may be drawn on top of the current power for the rest of the quarter-hour
+	var headroom float64
+	if remaining > 0 {
+		headroom = (threshold*QuarterHour.Seconds()-average*elapsed.Seconds())/remaining.Seconds() - power
+	}
+
+	return PeakProjection{
+		QuarterStart: t.quarterStart,
+		Elapsed:      elapsed,
+		PowerW:       power,
+		AverageW:     average,
+		ProjectedW:   projected,
+		MonthPeakW:   monthPeak,
+		ThresholdW:   threshold,
+		HeadroomW:    headroom,
+		Exceeding:    projected >= threshold-t.cfg.MarginW,
+	}
+}
+
+// quarterStartOf returns the start of the local quarter-hour containing t
+func quarterStartOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()/15*15, 0, 0, t.Location())
+}
@@ -0,0 +1,224 @@
+package device
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// fakePeakSource is a P1 meter stub for the peak tracker
+type fakePeakSource struct {
+	powerW    float64
+	powerErr  error
+	averageW  float64 // Meter's running average, used if averageOK
+	averageOK bool
+}
+
+func (s *fakePeakSource) GetPower() (float64, error) {
+	return s.powerW, s.powerErr
+}
+
+func (s *fakePeakSource) GetAveragePower15m() (float64, error) {
+	if !s.averageOK {
+		return 0, errors.New("not available")
+	}
+	return s.averageW, nil
+}
+
+func (s *fakePeakSource) GetMonthlyPowerPeak() (float64, time.Time, error) {
+	return 0, time.Time{}, errors.New("not available")
+}
+
+func newTestPeakTracker(src *fakePeakSource, cfg PeakTrackerConfig) *PeakTracker {
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultPeakInterval
+	}
+	if cfg.MinimumW == 0 {
+		cfg.MinimumW = DefaultMinimumPeakW
+	}
+	return &PeakTracker{src: src, cfg: cfg}
+}
+
+// run samples from start to end (exclusive) at the configured interval
+func (t *PeakTracker) run(start, end time.Time) {
+	for now := start; now.Before(end); now = now.Add(t.cfg.Interval) {
+		t.sample(now)
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestPeakTrackerProjection(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name          string
+		before, after float64 // Power for the first 5 minutes, then at the 5 minute sample
+		capW          float64
+		projected     float64
+		threshold     float64
+		headroom      float64
+		exceeding     bool
+	}{
+		// (2000*300 + 4000*600) / 900
+		{"rising", 2000, 4000, 0, 3333.333333, DefaultMinimumPeakW, (2500*900-2000*300)/600.0 - 4000, true},
+		{"steady", 2000, 2000, 0, 2000, DefaultMinimumPeakW, (2500*900-2000*300)/600.0 - 2000, false},
+		{"cap above minimum", 1000, 1000, 5000, 1000, 5000, (5000*900-1000*300)/600.0 - 1000, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := &fakePeakSource{powerW: tc.before}
+			tr := newTestPeakTracker(src, PeakTrackerConfig{CapW: tc.capW})
+
+			tr.run(start, start.Add(5*time.Minute))
+			src.powerW = tc.after
+			tr.sample(start.Add(5 * time.Minute))
+
+			p, err := tr.Projection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !approxEqual(p.AverageW, tc.before) {
+				t.Errorf("average = %v, want %v", p.AverageW, tc.before)
+			}
+			if math.Abs(p.ProjectedW-tc.projected) > 1e-3 {
+				t.Errorf("projected = %v, want %v", p.ProjectedW, tc.projected)
+			}
+			if p.ThresholdW != tc.threshold {
+				t.Errorf("threshold = %v, want %v", p.ThresholdW, tc.threshold)
+			}
+			if !approxEqual(p.HeadroomW, tc.headroom) {
+				t.Errorf("headroom = %v, want %v", p.HeadroomW, tc.headroom)
+			}
+			if p.Exceeding != tc.exceeding {
+				t.Errorf("exceeding = %v, want %v", p.Exceeding, tc.exceeding)
+			}
+		})
+	}
+}
+
+func TestPeakTrackerRollover(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		samples   func(tr *PeakTracker, src *fakePeakSource)
+		monthPeak float64
+	}{
+		{
+			name: "continuous quarter-hour",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				src.powerW = 3000
+				tr.run(start, start.Add(QuarterHour+time.Second))
+			},
+			monthPeak: 3000,
+		},
+		{
+			name: "gap after the quarter-hour",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				src.powerW = 3000
+				tr.run(start, start.Add(QuarterHour))
+				tr.sample(start.Add(QuarterHour + time.Hour))
+			},
+			// The last sample is 10 s before the end, the quarter-hour is still fully covered
+			monthPeak: 3000,
+		},
+		{
+			name: "offline for an hour",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				src.powerW = 3000
+				tr.run(start, start.Add(5*time.Minute))
+				tr.sample(start.Add(time.Hour + 5*time.Minute))
+			},
+			monthPeak: 0,
+		},
+		{
+			name: "power outage within the quarter-hour",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				src.powerW = 3000
+				tr.run(start, start.Add(5*time.Minute))
+				src.powerErr = errors.New("timeout")
+				tr.run(start.Add(5*time.Minute), start.Add(10*time.Minute))
+				src.powerErr = nil
+				tr.run(start.Add(10*time.Minute), start.Add(QuarterHour+time.Second))
+			},
+			monthPeak: 0,
+		},
+		{
+			name: "started mid-quarter",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				src.powerW = 3000
+				tr.run(start.Add(time.Minute), start.Add(2*QuarterHour+time.Second))
+			},
+			// Only the second quarter-hour is fully observed
+			monthPeak: 3000,
+		},
+		{
+			name: "month rollover",
+			samples: func(tr *PeakTracker, src *fakePeakSource) {
+				end := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+				src.powerW = 3000
+				tr.run(end.Add(-QuarterHour), end.Add(time.Second))
+			},
+			// The quarter-hour belongs to the previous month
+			monthPeak: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := &fakePeakSource{}
+			tr := newTestPeakTracker(src, PeakTrackerConfig{})
+			tc.samples(tr, src)
+
+			if !approxEqual(tr.monthPeakW, tc.monthPeak) {
+				t.Errorf("month peak = %v, want %v", tr.monthPeakW, tc.monthPeak)
+			}
+		})
+	}
+}
+
+func TestPeakTrackerMidQuarterAverage(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	// Started mid-quarter, the meter's running average is used
+	src := &fakePeakSource{powerW: 1000, averageW: 4000, averageOK: true}
+	tr := newTestPeakTracker(src, PeakTrackerConfig{})
+	tr.sample(start.Add(5 * time.Minute))
+
+	p, err := tr.Projection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AverageW != 4000 {
+		t.Errorf("average = %v, want 4000", p.AverageW)
+	}
+	if want := (4000*300 + 1000*600) / 900.0; !approxEqual(p.ProjectedW, want) {
+		t.Errorf("projected = %v, want %v", p.ProjectedW, want)
+	}
+}
+
+func TestPeakTrackerCallbacks(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	var exceeded, cleared int
+	src := &fakePeakSource{powerW: 1000}
+	tr := newTestPeakTracker(src, PeakTrackerConfig{
+		OnExceed: func(PeakProjection) { exceeded++ },
+		OnClear:  func(PeakProjection) { cleared++ },
+	})
+
+	tr.run(start, start.Add(time.Minute))
+	src.powerW = 5000
+	tr.run(start.Add(time.Minute), start.Add(2*time.Minute))
+	src.powerW = 1000
+	tr.run(start.Add(2*time.Minute), start.Add(3*time.Minute))
+
+	if exceeded != 1 || cleared != 1 {
+		t.Errorf("exceeded %d, cleared %d times, want 1 each", exceeded, cleared)
+	}
+}
@@ -0,0 +1,148 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Device is the common interface of all HomeWizard devices
+type Device interface {
+	Type() DeviceType
+	Host() string
+	Start(errC chan error)
+	StartAndWait(timeout time.Duration) error
+	Stop()
+}
+
+// Discovered is implemented by discovery results that can be turned into a device
+type Discovered interface {
+	DeviceHost() string
+	DeviceProductType() string
+	DeviceSerial() string
+}
+
+// Product describes a HomeWizard product type and how to talk to it
+type Product struct {
+	ProductType string        // e.g. "HWE-P1", as announced via mDNS and GET /api
+	DeviceType  DeviceType    // Device type the product maps to
+	Usage       string        // Default evcc meter usage, e.g. "grid", "pv", "battery", empty if not an evcc meter
+	Topics      []string      // Default WebSocket topics
+	Timeout     time.Duration // Data timeout of devices created by New, defaults to DefaultTimeout
+
+	// Decode unmarshals a measurement message of this product
+	// It must return the measurement type of the device created by New, e.g. KWHMeasurement for a KWHMeterDevice.
+	Decode func(data json.RawMessage) (any, error)
+
+	// New creates a device instance of this product
+	New func(host, token string, timeout time.Duration) Device
+}
+
+var (
+	productsMu sync.RWMutex
+	products   []Product
+)
+
+// RegisterProduct adds a product type to the registry, replacing an existing registration
+func RegisterProduct(p Product) {
+	if p.ProductType == "" || p.DeviceType == "" || p.Decode == nil || p.New == nil {
+		panic(fmt.Sprintf("invalid product registration: %+v", p))
+	}
+
+	productsMu.Lock()
+	defer productsMu.Unlock()
+
+	if idx := slices.IndexFunc(products, func(e Product) bool { return e.ProductType == p.ProductType }); idx >= 0 {
+		products[idx] = p
+		return
+	}
+
+	products = append(products, p)
+}
+
+// LookupProduct returns the registration of a product type
+func LookupProduct(productType string) (Product, bool) {
+	productsMu.RLock()
+	defer productsMu.RUnlock()
+
+	idx := slices.IndexFunc(products, func(e Product) bool { return e.ProductType == productType })
+	if idx < 0 {
+		return Product{}, false
+	}
+	return products[idx], true
+}
+
+// Products returns all registered product types in registration order
+func Products() []Product {
+	productsMu.RLock()
+	defer productsMu.RUnlock()
+	return slices.Clone(products)
+}
+
+// New creates a device for a discovered HomeWizard device using the product registry
+// Measurements of the device are decoded with the product's decoder.
+func New(d Discovered, token string) (Device, error) {
+	p, ok := LookupProduct(d.DeviceProductType())
+	if !ok {
+		return nil, fmt.Errorf("unknown product type: %s", d.DeviceProductType())
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dev := p.New(d.DeviceHost(), token, timeout)
+	if dd, ok := dev.(interface{ setDecoder(decoder) }); ok {
+		dd.setDecoder(p.Decode)
+	}
+
+	return dev, nil
+}
+
+// NewFromStore creates a device for a discovered HomeWizard device using the token stored for its serial
+// Devices found at a new address reconnect without pairing again
+func NewFromStore(d Discovered, store TokenStore) (Device, error) {
+	if d.DeviceSerial() == "" {
+		return nil, fmt.Errorf("no serial for %s, cannot look up token", d.DeviceHost())
+	}
+
+	token, err := store.Token(d.DeviceSerial())
+	if err != nil {
+		return nil, fmt.Errorf("token for %s: %w", d.DeviceSerial(), err)
+	}
+
+	return New(d, token)
+}
+
+// decoder unmarshals a measurement message, see Product.Decode
+type decoder = func(data json.RawMessage) (any, error)
+
+// decodeAs returns a measurement decoder for the given type
+func decodeAs[T any]() decoder {
+	return func(data json.RawMessage) (any, error) {
+		var m T
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// decodeMeasurement decodes a measurement message with the decoder of the device
+func decodeMeasurement[T any](decode decoder, data json.RawMessage) (T, error) {
+	var zero T
+
+	v, err := decode(data)
+	if err != nil {
+		return zero, err
+	}
+
+	m, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("decoder returned %T instead of %T", v, zero)
+	}
+	return m, nil
+}
@@ -0,0 +1,128 @@
+package device
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// discovered is a discovery result for tests
+type discovered struct {
+	host, productType string
+}
+
+func (d discovered) DeviceHost() string        { return d.host }
+func (d discovered) DeviceProductType() string { return d.productType }
+func (d discovered) DeviceSerial() string      { return "" }
+
+// deviceTimeout returns the data timeout of a device
+func deviceTimeout(d Device) time.Duration {
+	switch d := d.(type) {
+	case *P1MeterDevice:
+		return d.timeout
+	case *KWHMeterDevice:
+		return d.timeout
+	case *BatteryDevice:
+		return d.timeout
+	case *EnergySocketDevice:
+		return d.timeout
+	case *WaterMeterDevice:
+		return d.timeout
+	default:
+		return 0
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		productType string
+		deviceType  DeviceType
+		timeout     time.Duration
+	}{
+		{"HWE-P1", DeviceTypeP1Meter, DefaultTimeout},
+		{"HWE-KWH1", DeviceTypeKWHMeter, DefaultTimeout},
+		{"HWE-KWH3", DeviceTypeKWHMeter, DefaultTimeout},
+		{"HWE-BAT", DeviceTypeBattery, DefaultTimeout},
+		{"HWE-SKT", DeviceTypeEnergySocket, DefaultTimeout},
+		{"HWE-WTR", DeviceTypeWaterMeter, DefaultWaterTimeout},
+	}
+
+	for _, tc := range tests {
+		d, err := New(discovered{"192.0.2.1", tc.productType}, "token")
+		if err != nil {
+			t.Errorf("%s: %v", tc.productType, err)
+			continue
+		}
+
+		if d.Type() != tc.deviceType {
+			t.Errorf("%s: type = %s, want %s", tc.productType, d.Type(), tc.deviceType)
+		}
+
+		if timeout := deviceTimeout(d); timeout != tc.timeout {
+			t.Errorf("%s: timeout = %v, want %v", tc.productType, timeout, tc.timeout)
+		}
+	}
+
+	if _, err := New(discovered{"192.0.2.1", "HWE-UNKNOWN"}, "token"); err == nil {
+		t.Error("unknown product type accepted")
+	}
+}
+
+func TestNewUsesProductDecoder(t *testing.T) {
+	var calls int
+	RegisterProduct(Product{
+		ProductType: "HWE-TEST",
+		DeviceType:  DeviceTypeKWHMeter,
+		Decode: func(data json.RawMessage) (any, error) {
+			calls++
+			var m KWHMeasurement
+			m.PowerW = 42
+			return m, nil
+		},
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewKWHMeterDevice(host, token, timeout)
+		},
+	})
+	t.Cleanup(func() {
+		productsMu.Lock()
+		defer productsMu.Unlock()
+		products = slices.DeleteFunc(products, func(p Product) bool { return p.ProductType == "HWE-TEST" })
+	})
+
+	d, err := New(discovered{"192.0.2.1", "HWE-TEST"}, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meter := d.(*KWHMeterDevice)
+	if err := meter.handleMessage("measurement", json.RawMessage(`{"power_w":100}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := meter.GetMeasurement(); calls != 1 || err != nil || m.PowerW != 42 {
+		t.Errorf("decoder calls = %d, power = %v, %v, want the product decoder's 42 W", calls, m.PowerW, err)
+	}
+
+	// A decoder returning the wrong measurement type is rejected
+	meter.setDecoder(decodeAs[WaterMeasurement]())
+	if err := meter.handleMessage("measurement", json.RawMessage(`{}`)); err == nil {
+		t.Error("measurement of the wrong type accepted")
+	}
+}
+
+func TestRegisterProductRequiresDecoder(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("product without decoder registered")
+		}
+	}()
+
+	RegisterProduct(Product{
+		ProductType: "HWE-TEST",
+		DeviceType:  DeviceTypeKWHMeter,
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewKWHMeterDevice(host, token, timeout)
+		},
+	})
+}
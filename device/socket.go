@@ -0,0 +1,210 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/evcc-io/evcc/util"
+	"github.com/evcc-io/evcc/util/request"
+)
+
+// MaxSocketBrightness is the maximum LED ring brightness of an energy socket
+const MaxSocketBrightness = 255
+
+// ErrSwitchLocked is returned when switching off an energy socket with switch lock enabled
+var ErrSwitchLocked = errors.New("switch lock enabled")
+
+// SocketMeasurement contains measurements from energy sockets (HWE-SKT)
+type SocketMeasurement struct {
+	CommonMeasurement
+
+	// Energy measurements
+	EnergyImportkWh float64 `json:"energy_import_kwh"`
+	EnergyExportkWh float64 `json:"energy_export_kwh"`
+
+	// Electrical measurements
+	ApparentPowerVA  float64 `json:"apparent_power_va"`
+	ReactivePowerVAR float64 `json:"reactive_power_var"`
+	PowerFactor      float64 `json:"power_factor"`
+	FrequencyHz      float64 `json:"frequency_hz"`
+}
+
+func (m SocketMeasurement) GetCommon() CommonMeasurement { return m.CommonMeasurement }
+
+// SocketState contains the switch state of an energy socket
+type SocketState struct {
+	PowerOn    bool `json:"power_on"`
+	SwitchLock bool `json:"switch_lock"` // Socket can't be switched off while locked
+	Brightness int  `json:"brightness"`  // LED ring brightness 0-255
+}
+
+// socketStateRequest is the payload for changing the socket state, nil fields are left unchanged
+type socketStateRequest struct {
+	PowerOn    *bool `json:"power_on,omitempty"`
+	SwitchLock *bool `json:"switch_lock,omitempty"`
+	Brightness *int  `json:"brightness,omitempty"`
+}
+
+// socketTopics are the WebSocket topics of energy sockets
+var socketTopics = []string{"measurement", "state"}
+
+func init() {
+	RegisterProduct(Product{
+		ProductType: "HWE-SKT",
+		DeviceType:  DeviceTypeEnergySocket,
+		Usage:       "charge",
+		Topics:      socketTopics,
+		Decode:      decodeAs[SocketMeasurement](),
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewEnergySocketDevice(host, token, timeout)
+		},
+	})
+}
+
+// EnergySocketDevice represents an energy socket (HWE-SKT) with switch control
+type EnergySocketDevice struct {
+	*baseMeterDevice[SocketMeasurement]
+	state *util.Monitor[SocketState]
+}
+
+// NewEnergySocketDevice creates a new energy socket device instance
+func NewEnergySocketDevice(host, token string, timeout time.Duration) *EnergySocketDevice {
+	d := &EnergySocketDevice{
+		baseMeterDevice: &baseMeterDevice[SocketMeasurement]{
+			deviceBase:  newDeviceBase(DeviceTypeEnergySocket, decodeAs[SocketMeasurement](), host, token, timeout),
+			measurement: util.NewMonitor[SocketMeasurement](timeout),
+		},
+		state: util.NewMonitor[SocketState](timeout),
+	}
+
+	// Create connection with message handler, subscribe to measurement and state topics
+	d.conn = NewConnection(host, token, d.handleSocketMessage, socketTopics...)
+
+	return d
+}
+
+// handleSocketMessage extends base message handling with state messages
+func (d *EnergySocketDevice) handleSocketMessage(msgType string, data json.RawMessage) error {
+	switch msgType {
+	case "state":
+		var s SocketState
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("unmarshal socket state: %w", err)
+		}
+		d.state.Set(s)
+		return nil
+
+	default:
+		// Delegate to baseMeterDevice for measurement and other messages
+		return d.baseMeterDevice.handleMessage(msgType, data)
+	}
+}
+
+// GetTotalEnergy returns the total imported energy (consumption)
+func (d *EnergySocketDevice) GetTotalEnergy() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return m.EnergyImportkWh, nil
+}
+
+// GetState returns the latest socket state
+func (d *EnergySocketDevice) GetState() (SocketState, error) {
+	s, err := d.state.Get()
+	if err != nil {
+		return SocketState{}, api.ErrTimeout
+	}
+	return s, nil
+}
+
+// IsPowerOn returns true if the socket relay is switched on
+func (d *EnergySocketDevice) IsPowerOn() (bool, error) {
+	s, err := d.GetState()
+	if err != nil {
+		return false, err
+	}
+	return s.PowerOn, nil
+}
+
+// SetPowerOn switches the socket relay on or off
+// A locked socket stays on, switching it on is a no-op and switching it off returns ErrSwitchLocked
+func (d *EnergySocketDevice) SetPowerOn(on bool) error {
+	if s, err := d.GetState(); err == nil && s.SwitchLock {
+		if on {
+			return nil
+		}
+		return ErrSwitchLocked
+	}
+
+	d.log.INFO.Printf("setting socket power to: %t", on)
+
+	return d.setState(socketStateRequest{PowerOn: &on})
+}
+
+// SetSwitchLock enables or disables the switch lock
+// A locked socket stays on and can't be switched via the button or the API
+func (d *EnergySocketDevice) SetSwitchLock(lock bool) error {
+	d.log.INFO.Printf("setting socket switch lock to: %t", lock)
+
+	return d.setState(socketStateRequest{SwitchLock: &lock})
+}
+
+// SetBrightness sets the LED ring brightness (0-255)
+func (d *EnergySocketDevice) SetBrightness(brightness int) error {
+	if brightness < 0 || brightness > MaxSocketBrightness {
+		return fmt.Errorf("invalid brightness: %d", brightness)
+	}
+
+	d.log.DEBUG.Printf("setting socket brightness to: %d", brightness)
+
+	return d.setState(socketStateRequest{Brightness: &brightness})
+}
+
+// setState sends a state request via WebSocket, falling back to HTTP
+func (d *EnergySocketDevice) setState(reqBody socketStateRequest) error {
+	// Try WebSocket control first
+	wsMsg := struct {
+		Type string             `json:"type"`
+		Data socketStateRequest `json:"data"`
+	}{
+		Type: "state",
+		Data: reqBody,
+	}
+
+	if err := d.conn.Send(wsMsg); err != nil {
+		d.log.DEBUG.Printf("WebSocket socket control failed, falling back to HTTP: %v", err)
+		return d.setStateHTTP(reqBody)
+	}
+
+	// Give the device a moment to process
+	time.Sleep(100 * time.Millisecond)
+	d.log.DEBUG.Println("WebSocket socket control sent")
+	return nil
+}
+
+// setStateHTTP sets the socket state via HTTP PUT
+func (d *EnergySocketDevice) setStateHTTP(reqBody socketStateRequest) error {
+	uri := fmt.Sprintf("https://%s/api/state", d.host)
+	d.log.DEBUG.Printf("sending HTTP PUT to %s", uri)
+
+	req, err := NewAPIRequest(context.Background(), http.MethodPut, uri, d.token, request.MarshalJSON(reqBody))
+	if err != nil {
+		return err
+	}
+
+	var res SocketState
+	if err := d.DoJSON(req, &res); err != nil {
+		return err
+	}
+
+	d.state.Set(res)
+
+	d.log.DEBUG.Printf("socket state set via HTTP (power_on=%t, switch_lock=%t, brightness=%d)", res.PowerOn, res.SwitchLock, res.Brightness)
+	return nil
+}
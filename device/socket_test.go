@@ -0,0 +1,21 @@
+package device
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSocketSwitchLock(t *testing.T) {
+	d := NewEnergySocketDevice("192.0.2.1", "token", time.Minute)
+	d.state.Set(SocketState{PowerOn: true, SwitchLock: true})
+
+	// Locked sockets stay on, switching on must not fail
+	if err := d.SetPowerOn(true); err != nil {
+		t.Errorf("switching on a locked socket: %v", err)
+	}
+
+	if err := d.SetPowerOn(false); !errors.Is(err, ErrSwitchLocked) {
+		t.Errorf("switching off a locked socket: %v, want ErrSwitchLocked", err)
+	}
+}
@@ -0,0 +1,40 @@
+package device
+
+import (
+	"context"
+	"net/http"
+)
+
+// SystemInfo contains the system settings and status reported by GET /api/system
+type SystemInfo struct {
+	WifiSSID               string  `json:"wifi_ssid"`
+	WifiRSSIdB             float64 `json:"wifi_rssi_db"`
+	UptimeS                int64   `json:"uptime_s"`
+	CloudEnabled           bool    `json:"cloud_enabled"`
+	StatusLEDBrightnessPct int     `json:"status_led_brightness_pct"`
+	APIV1Enabled           *bool   `json:"api_v1_enabled,omitempty"` // Not all products offer the v1 API
+}
+
+// GetInfo returns the device information, no token is required
+func GetInfo(ctx context.Context, host string) (DeviceInfo, error) {
+	var res DeviceInfo
+	err := doAPI(ctx, host, "", http.MethodGet, "/api", nil, &res)
+	return res, err
+}
+
+// GetSystem returns the system settings and status of the device
+func GetSystem(ctx context.Context, host, token string) (SystemInfo, error) {
+	var res SystemInfo
+	err := doAPI(ctx, host, token, http.MethodGet, "/api/system", nil, &res)
+	return res, err
+}
+
+// Identify makes the status LED of the device blink
+func Identify(ctx context.Context, host, token string) error {
+	return doAPI(ctx, host, token, http.MethodPut, "/api/system/identify", nil, nil)
+}
+
+// Reboot restarts the device, it is unavailable until it has reconnected to the network
+func Reboot(ctx context.Context, host, token string) error {
+	return doAPI(ctx, host, token, http.MethodPut, "/api/system/reboot", nil, nil)
+}
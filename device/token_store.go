@@ -0,0 +1,232 @@
+package device
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/mluiten/evcc-homewizard-v2/internal/atomicfile"
+)
+
+// Token store parameters
+const (
+	tokenFileVersion   = 1
+	tokenFileMode      = 0o600
+	tokenKeyIterations = 600_000 // PBKDF2-SHA256 iterations, as recommended by OWASP
+	tokenKeySize       = 32      // AES-256
+	tokenSaltSize      = 16
+)
+
+// ErrTokenNotFound is returned when no token is stored for a serial
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenStore persists device tokens keyed by device serial
+type TokenStore interface {
+	Token(serial string) (string, error) // Returns ErrTokenNotFound if no token is stored
+	SaveToken(serial, token string) error
+	DeleteToken(serial string) error
+}
+
+// tokenFile is the on-disk format of the file token store
+type tokenFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"` // AES-GCM encrypted JSON map of serial to token
+}
+
+// FileTokenStore persists tokens in a file encrypted with a key derived from a user supplied passphrase
+type FileTokenStore struct {
+	mu         sync.Mutex
+	path       string
+	passphrase string
+	salt       []byte // Salt the cached key was derived with
+	key        []byte
+}
+
+// NewFileTokenStore creates a token store backed by the given file
+// The file is created on the first save, the same passphrase is required to read it again
+func NewFileTokenStore(path, passphrase string) (*FileTokenStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("token store passphrase must not be empty")
+	}
+
+	return &FileTokenStore{
+		path:       path,
+		passphrase: passphrase,
+	}, nil
+}
+
+// Token returns the stored token of the serial
+func (s *FileTokenStore) Token(serial string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := tokens[serial]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// SaveToken stores the token of the serial, replacing an existing token
+func (s *FileTokenStore) SaveToken(serial, token string) error {
+	if serial == "" {
+		return errors.New("serial must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	tokens[serial] = token
+	return s.save(tokens)
+}
+
+// DeleteToken removes the token of the serial, if any
+func (s *FileTokenStore) DeleteToken(serial string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tokens[serial]; !ok {
+		return nil
+	}
+
+	delete(tokens, serial)
+	return s.save(tokens)
+}
+
+// Serials returns the serials with a stored token
+func (s *FileTokenStore) Serials() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(tokens))
+	for serial := range tokens {
+		res = append(res, serial)
+	}
+	slices.Sort(res)
+
+	return res, nil
+}
+
+// load reads and decrypts the tokens, a missing file is an empty store
+func (s *FileTokenStore) load() (map[string]string, error) {
+	tokens := make(map[string]string)
+
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f tokenFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	if f.Version != tokenFileVersion {
+		return nil, fmt.Errorf("parse %s: unsupported version %d", s.path, f.Version)
+	}
+
+	gcm, err := s.cipher(f.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: wrong passphrase or corrupted file", s.path)
+	}
+
+	if err := json.Unmarshal(plain, &tokens); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+
+	return tokens, nil
+}
+
+// save encrypts and atomically writes the tokens
+func (s *FileTokenStore) save(tokens map[string]string) error {
+	salt := s.salt
+	if salt == nil {
+		salt = make([]byte, tokenSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(tokenFile{
+		Version: tokenFileVersion,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(s.path, b, tokenFileMode)
+}
+
+// cipher returns the AES-GCM cipher for the salt, deriving the key only when the salt changed
+func (s *FileTokenStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.key == nil || !slices.Equal(s.salt, salt) {
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, tokenKeyIterations, tokenKeySize)
+		if err != nil {
+			return nil, err
+		}
+		s.salt, s.key = slices.Clone(salt), key
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
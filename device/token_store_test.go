@@ -0,0 +1,80 @@
+package device
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	s, err := NewFileTokenStore(path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing file is an empty store
+	if _, err := s.Token("abc"); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("token of empty store: %v, want ErrTokenNotFound", err)
+	}
+
+	for serial, token := range map[string]string{"abc": "token-1", "def": "token-2"} {
+		if err := s.SaveToken(serial, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != tokenFileMode {
+		t.Errorf("file mode = %v, want %v", fi.Mode().Perm(), os.FileMode(tokenFileMode))
+	}
+
+	// A new store with the same passphrase reads the tokens back
+	s, err = NewFileTokenStore(path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serial, token string
+		err           error
+	}{
+		{"abc", "token-1", nil},
+		{"def", "token-2", nil},
+		{"xyz", "", ErrTokenNotFound},
+	}
+
+	for _, tc := range tests {
+		token, err := s.Token(tc.serial)
+		if token != tc.token || !errors.Is(err, tc.err) {
+			t.Errorf("Token(%q) = %q, %v, want %q, %v", tc.serial, token, err, tc.token, tc.err)
+		}
+	}
+
+	if err := s.DeleteToken("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	serials, err := s.Serials()
+	if err != nil || !slices.Equal(serials, []string{"def"}) {
+		t.Errorf("serials after delete = %v, %v, want [def]", serials, err)
+	}
+
+	wrong, err := NewFileTokenStore(path, "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Token("def"); err == nil {
+		t.Error("wrong passphrase decrypted the store")
+	}
+
+	if _, err := NewFileTokenStore(path, ""); err == nil {
+		t.Error("empty passphrase accepted")
+	}
+}
@@ -1,6 +1,10 @@
 package device
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
 
 // Message is the base WebSocket message format
 type Message struct {
@@ -8,13 +12,74 @@ type Message struct {
 	Data json.RawMessage `json:"data,omitempty"`
 }
 
-// BatteriesData contains battery system status from P1 meter
+// BatteryMode is the battery control mode of the P1 batteries API
+type BatteryMode string
+
+const (
+	BatteryModeZero    BatteryMode = "zero"    // Charge and discharge to keep grid power at zero
+	BatteryModeToFull  BatteryMode = "to_full" // Charge to full, regardless of grid power
+	BatteryModeStandby BatteryMode = "standby" // Neither charge nor discharge
+)
+
+// ParseBatteryMode validates and converts a string to a BatteryMode
+func ParseBatteryMode(s string) (BatteryMode, error) {
+	mode := BatteryMode(s)
+	if !mode.Valid() {
+		return "", fmt.Errorf("invalid battery mode: %s", s)
+	}
+	return mode, nil
+}
+
+// Valid returns true if the mode is a known battery mode
+func (m BatteryMode) Valid() bool {
+	switch m {
+	case BatteryModeZero, BatteryModeToFull, BatteryModeStandby:
+		return true
+	default:
+		return false
+	}
+}
+
+// BatteryPermission restricts what the batteries may do in the current mode
+type BatteryPermission string
+
+const (
+	BatteryPermissionCharge    BatteryPermission = "charge_allowed"
+	BatteryPermissionDischarge BatteryPermission = "discharge_allowed"
+)
+
+// Valid returns true if the permission is a known battery permission
+func (p BatteryPermission) Valid() bool {
+	return p == BatteryPermissionCharge || p == BatteryPermissionDischarge
+}
+
+// BatteriesState contains battery system status from P1 meter
 // Used for battery control responses and power limits
-type BatteriesData struct {
-	Mode            string  `json:"mode"`              // "zero", "to_full", "standby"
-	PowerW          float64 `json:"power_w"`           // Combined battery power
-	MaxConsumptionW float64 `json:"max_consumption_w"` // Maximum charge power
-	MaxProductionW  float64 `json:"max_production_w"`  // Maximum discharge power
+type BatteriesState struct {
+	Mode            BatteryMode         `json:"mode"`
+	Permissions     []BatteryPermission `json:"permissions,omitempty"`   // Newer firmware only
+	BatteryCount    int                 `json:"battery_count,omitempty"` // Newer firmware only
+	PowerW          float64             `json:"power_w"`                 // Combined battery power
+	TargetPowerW    float64             `json:"target_power_w"`          // Newer firmware only
+	MaxConsumptionW float64             `json:"max_consumption_w"`       // Maximum charge power
+	MaxProductionW  float64             `json:"max_production_w"`        // Maximum discharge power
+}
+
+// BatteriesData is the former name of BatteriesState
+//
+// Deprecated: use BatteriesState
+type BatteriesData = BatteriesState
+
+// ChargeAllowed returns true if the batteries may charge
+// Firmware without permissions support always allows charging
+func (s BatteriesState) ChargeAllowed() bool {
+	return s.Permissions == nil || slices.Contains(s.Permissions, BatteryPermissionCharge)
+}
+
+// DischargeAllowed returns true if the batteries may discharge
+// Firmware without permissions support always allows discharging
+func (s BatteriesState) DischargeAllowed() bool {
+	return s.Permissions == nil || slices.Contains(s.Permissions, BatteryPermissionDischarge)
 }
 
 // AuthRequest is sent by the server requesting authorization
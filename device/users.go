@@ -0,0 +1,54 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/evcc-io/evcc/util"
+	"github.com/evcc-io/evcc/util/request"
+)
+
+// User is a local API user of a device
+type User struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"` // The user of the token used for the request
+}
+
+// ListUsers returns the API users of the device
+func ListUsers(ctx context.Context, host, token string) ([]User, error) {
+	var res []User
+	err := doAPI(ctx, host, token, http.MethodGet, "/api/user", nil, &res)
+	return res, err
+}
+
+// DeleteUser removes an API user from the device, invalidating its token
+// The device does not allow deleting the user of the token used for the request.
+func DeleteUser(ctx context.Context, host, token, name string) error {
+	body := struct {
+		Name string `json:"name"`
+	}{
+		Name: name,
+	}
+
+	return doAPI(ctx, host, token, http.MethodDelete, "/api/user", request.MarshalJSON(body), nil)
+}
+
+// doAPI performs a HomeWizard API v2 request and decodes the response into res, if not nil
+// The request is unauthenticated if the token is empty.
+func doAPI(ctx context.Context, host, token, method, path string, body io.Reader, res any) error {
+	helper := newAPIHelper(util.NewLogger("homewizard-v2").Redact(token))
+
+	req, err := NewAPIRequest(ctx, method, fmt.Sprintf("https://%s%s", host, path), token, body)
+	if err != nil {
+		return err
+	}
+
+	if res == nil {
+		_, err = helper.DoBody(req)
+		return err
+	}
+
+	return helper.DoJSON(req, res)
+}
@@ -0,0 +1,60 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DeviceInfo contains the device information reported by GET /api and the "device" topic
+type DeviceInfo struct {
+	ProductName     string `json:"product_name"`
+	ProductType     string `json:"product_type"`
+	Serial          string `json:"serial"`
+	FirmwareVersion string `json:"firmware_version"`
+	APIVersion      string `json:"api_version"`
+}
+
+// Verify checks that the token authenticates on the device WebSocket and returns the device information
+// Returns an error wrapping ErrUnauthorized if the token is rejected.
+func Verify(ctx context.Context, host, token string) (DeviceInfo, error) {
+	infoC := make(chan DeviceInfo, 1)
+	errC := make(chan error, 1)
+
+	conn := NewConnection(host, token, func(msgType string, data json.RawMessage) error {
+		if msgType != "device" {
+			return nil
+		}
+
+		var info DeviceInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return fmt.Errorf("unmarshal device info: %w", err)
+		}
+
+		select {
+		case infoC <- info:
+		default:
+		}
+		return nil
+	}, "device")
+
+	conn.Start(errC)
+	defer conn.Stop()
+
+	// The error channel is closed once connected and authenticated
+	select {
+	case err := <-errC:
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+	case <-ctx.Done():
+		return DeviceInfo{}, ctx.Err()
+	}
+
+	select {
+	case info := <-infoC:
+		return info, nil
+	case <-ctx.Done():
+		return DeviceInfo{}, fmt.Errorf("waiting for device info: %w", ctx.Err())
+	}
+}
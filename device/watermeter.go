@@ -0,0 +1,127 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/evcc-io/evcc/api"
+	"github.com/evcc-io/evcc/util"
+)
+
+// WaterPowerSource is how a watermeter is powered
+type WaterPowerSource string
+
+const (
+	WaterPowerSourceBattery WaterPowerSource = "battery" // Wakes up periodically, measurements are sparse
+	WaterPowerSourceUSB     WaterPowerSource = "usb"     // Always connected, realtime measurements
+)
+
+// WaterMeasurement contains measurement data from watermeters (HWE-WTR)
+type WaterMeasurement struct {
+	// Water measurements
+	TotalLiterM3       float64 `json:"total_liter_m3"`
+	TotalLiterOffsetM3 float64 `json:"total_liter_offset_m3"` // Configured meter reading offset
+	ActiveLiterLPM     float64 `json:"active_liter_lpm"`
+
+	// Power supply
+	PowerSource WaterPowerSource `json:"power_source,omitempty"` // Empty on older firmware
+}
+
+// waterTopics are the WebSocket topics of watermeters
+var waterTopics = []string{"measurement"}
+
+func init() {
+	RegisterProduct(Product{
+		ProductType: "HWE-WTR",
+		DeviceType:  DeviceTypeWaterMeter,
+		Topics:      waterTopics,
+		Timeout:     DefaultWaterTimeout,
+		Decode:      decodeAs[WaterMeasurement](),
+		New: func(host, token string, timeout time.Duration) Device {
+			return NewWaterMeterDevice(host, token, timeout)
+		},
+	})
+}
+
+// WaterMeterDevice represents a watermeter (HWE-WTR) for water usage monitoring
+type WaterMeterDevice struct {
+	*deviceBase
+	measurement *util.Monitor[WaterMeasurement]
+}
+
+// NewWaterMeterDevice creates a new watermeter device instance
+// Battery powered watermeters only report when they wake up, so use a generous timeout
+func NewWaterMeterDevice(host, token string, timeout time.Duration) *WaterMeterDevice {
+	d := &WaterMeterDevice{
+		deviceBase:  newDeviceBase(DeviceTypeWaterMeter, decodeAs[WaterMeasurement](), host, token, timeout),
+		measurement: util.NewMonitor[WaterMeasurement](timeout),
+	}
+
+	// Create connection with message handler
+	d.conn = NewConnection(host, token, d.handleMessage, waterTopics...)
+
+	return d
+}
+
+// handleMessage routes incoming WebSocket messages for watermeter
+func (d *WaterMeterDevice) handleMessage(msgType string, data json.RawMessage) error {
+	switch msgType {
+	case "measurement":
+		m, err := decodeMeasurement[WaterMeasurement](d.decode, data)
+		if err != nil {
+			return fmt.Errorf("unmarshal water measurement: %w", err)
+		}
+		d.measurement.Set(m)
+		d.log.TRACE.Printf("updated water measurement: total=%.3fm³, flow=%.1fl/min", m.TotalLiterM3, m.ActiveLiterLPM)
+
+	case "device", "system":
+		// Ignore device info and system messages
+		d.log.TRACE.Printf("ignoring message type: %s", msgType)
+
+	default:
+		d.log.TRACE.Printf("unknown message type: %s", msgType)
+	}
+
+	return nil
+}
+
+// GetMeasurement returns the latest watermeter measurement data
+func (d *WaterMeterDevice) GetMeasurement() (WaterMeasurement, error) {
+	m, err := d.measurement.Get()
+	if err != nil {
+		return WaterMeasurement{}, api.ErrTimeout
+	}
+	return m, nil
+}
+
+// GetTotalLiters returns the total water usage in liters, including the configured offset
+func (d *WaterMeterDevice) GetTotalLiters() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return (m.TotalLiterM3 + m.TotalLiterOffsetM3) * 1000, nil
+}
+
+// GetFlowRate returns the active water flow in liters per minute
+func (d *WaterMeterDevice) GetFlowRate() (float64, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return 0, err
+	}
+	return m.ActiveLiterLPM, nil
+}
+
+// IsBatteryPowered returns true if the watermeter runs on batteries
+// Returns api.ErrNotAvailable if the firmware doesn't report the power source
+func (d *WaterMeterDevice) IsBatteryPowered() (bool, error) {
+	m, err := d.GetMeasurement()
+	if err != nil {
+		return false, err
+	}
+	if m.PowerSource == "" {
+		return false, api.ErrNotAvailable
+	}
+	return m.PowerSource == WaterPowerSourceBattery, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,90 +13,259 @@ import (
 	"github.com/mluiten/evcc-homewizard-v2/device"
 )
 
+// mDNS services announced by HomeWizard devices
+const (
+	serviceV2     = "_homewizard._tcp" // API v2
+	serviceLegacy = "_hwenergy._tcp"   // API v1, also announced with the local API disabled
+)
+
+// Status classifies whether a discovered device can be used with this library
+type Status string
+
+const (
+	StatusReady       Status = "v2-ready"     // API v2 available
+	StatusV1Only      Status = "v1-only"      // Only the legacy v1 API is announced, firmware update required
+	StatusAPIDisabled Status = "api-disabled" // Local API switched off in the HomeWizard Energy app
+)
+
+// rank orders statuses from most to least usable
+func (s Status) rank() int {
+	switch s {
+	case StatusReady:
+		return 0
+	case StatusV1Only:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // DiscoveredDevice represents a discovered HomeWizard device
 type DiscoveredDevice struct {
-	Instance string
-	Host     string
-	Port     int
-	Type     device.DeviceType
+	Status      Status
+	Instance    string
+	Serial      string
+	Host        string
+	Addresses   []string // All known IP addresses of the device
+	Interfaces  []string // Network interfaces the device was seen on
+	Port        int
+	Type        device.DeviceType
+	ProductType string // e.g. "HWE-P1"
+	ProductName string // e.g. "P1 meter"
+	APIVersion  string // Advertised API version, e.g. "2.0.0"
+	APIEnabled  bool   // Local API enabled, assumed true if not advertised
+	Path        string // API base path, only advertised by the v1 service
+	TXT         map[string]string
+}
+
+// txtRecord contains the parsed TXT records of a HomeWizard mDNS announcement
+type txtRecord struct {
+	ProductType string
+	ProductName string
+	Serial      string
+	APIVersion  string
+	APIEnabled  bool
+	Path        string
+	Raw         map[string]string
+}
+
+// parseTXT parses all TXT records, which are in format "key=value", e.g., "product_type=HWE-P1"
+func parseTXT(txtRecords []string) txtRecord {
+	raw := make(map[string]string, len(txtRecords))
+	for _, txt := range txtRecords {
+		if key, value, found := strings.Cut(txt, "="); found {
+			raw[key] = value
+		}
+	}
+
+	res := txtRecord{
+		ProductType: raw["product_type"],
+		ProductName: raw["product_name"],
+		Serial:      raw["serial"],
+		APIVersion:  raw["api_version"],
+		APIEnabled:  true,
+		Path:        raw["path"],
+		Raw:         raw,
+	}
+
+	if v, ok := raw["api_enabled"]; ok {
+		res.APIEnabled = v == "1" || v == "true"
+	}
+
+	return res
+}
+
+// DeviceHost implements the device.Discovered interface
+func (d DiscoveredDevice) DeviceHost() string {
+	return d.Host
+}
+
+// DeviceProductType implements the device.Discovered interface
+func (d DiscoveredDevice) DeviceProductType() string {
+	return d.ProductType
+}
+
+// DeviceSerial implements the device.Discovered interface
+func (d DiscoveredDevice) DeviceSerial() string {
+	return d.Serial
+}
+
+// merge adds addresses and interfaces not yet known to the device
+// If the other record has a more usable status, e.g. a v2 announcement of a device previously
+// only seen on the legacy service, its details replace the current ones
+func (d *DiscoveredDevice) merge(other DiscoveredDevice) bool {
+	var changed bool
+	if other.Status.rank() < d.Status.rank() {
+		addrs, ifaces := d.Addresses, d.Interfaces
+		*d = other
+		d.Addresses, d.Interfaces = slices.Clone(addrs), slices.Clone(ifaces)
+		changed = true
+	}
+	for _, addr := range other.Addresses {
+		if !slices.Contains(d.Addresses, addr) {
+			d.Addresses = append(d.Addresses, addr)
+			changed = true
+		}
+	}
+	for _, iface := range other.Interfaces {
+		if !slices.Contains(d.Interfaces, iface) {
+			d.Interfaces = append(d.Interfaces, iface)
+			changed = true
+		}
+	}
+	return changed
 }
 
 // DiscoverDevices scans the network for HomeWizard devices (P1 meters and batteries)
-// Calls onDevice for each discovered device. Returns when context is cancelled or timeout expires.
-func DiscoverDevices(ctx context.Context, onDevice func(DiscoveredDevice)) error {
+// Devices only announcing the legacy v1 service or with the local API disabled are reported as well,
+// see DiscoveredDevice.Status. Calls onDevice once per device, and again if its status improves
+// (e.g. the v2 announcement of a device first seen on the legacy service).
+// Returns when context is cancelled or timeout expires.
+func DiscoverDevices(ctx context.Context, onDevice func(DiscoveredDevice), opts ...Option) error {
 	logger := log.Default()
-	logger.Printf("starting mDNS discovery for _homewizard._tcp")
+	logger.Printf("starting mDNS discovery for %s and %s", serviceV2, serviceLegacy)
 
-	entries := make(chan *zeroconf.ServiceEntry, 10)
+	o := applyOptions(opts...)
+	entries := make(chan browsedEntry, 10)
+	done := make(chan struct{})
 
 	// Collect entries in a goroutine
+	// The same device may be announced on several interfaces, so entries are deduplicated by serial
 	go func() {
-		for entry := range entries {
-			// Log raw DNS record details
-			logger.Printf("mDNS entry: Instance=%s, HostName=%s, Port=%d, AddrIPv4=%v, AddrIPv6=%v, Text=%v",
-				entry.Instance, entry.HostName, entry.Port, entry.AddrIPv4, entry.AddrIPv6, entry.Text)
-
-			// Extract product_type from TXT records
-			productType := extractProductType(entry.Text)
-			if productType == "" {
-				logger.Printf("skipping device %s: no product_type in TXT records", entry.Instance)
-				continue
-			}
+		defer close(done)
+
+		seen := make(map[string]*DiscoveredDevice)
 
-			// Determine device type from product_type TXT record
-			var deviceType device.DeviceType
-			switch productType {
-			case "HWE-P1":
-				deviceType = device.DeviceTypeP1Meter
-			case "HWE-KWH1", "HWE-KWH3":
-				deviceType = device.DeviceTypeKWHMeter
-			case "HWE-BAT":
-				deviceType = device.DeviceTypeBattery
-			default:
-				// Skip unknown product types
-				logger.Printf("skipping device %s: unknown product_type=%s", entry.Instance, productType)
+		for e := range entries {
+			device, ok := parseEntry(e.entry, e.service, e.iface, o, logger)
+			if !ok {
 				continue
 			}
 
-			// Resolve the best hostname or IP address to use
-			host := resolveHost(entry.HostName, entry.AddrIPv4, logger)
-
-			device := DiscoveredDevice{
-				Instance: entry.Instance,
-				Host:     host,
-				Port:     entry.Port,
-				Type:     deviceType,
+			key := deviceKey(device)
+			if known, ok := seen[key]; ok {
+				status := known.Status
+				if known.merge(device) {
+					logger.Printf("additional addresses for %s: %v on %v", key, known.Addresses, known.Interfaces)
+				}
+				if known.Status != status {
+					logger.Printf("status of %s changed to %s", key, known.Status)
+					onDevice(*known)
+				}
+				continue
 			}
 
-			logger.Printf("discovered %s: %s at %s:%d", deviceType, entry.Instance, host, entry.Port)
+			seen[key] = &device
 			onDevice(device)
 		}
 	}()
 
-	// Browse for HomeWizard devices using the _homewizard._tcp service
-	// The entries channel will be closed by zeroconf when done
-	if err := zeroconf.Browse(ctx, "_homewizard._tcp", "local.", entries); err != nil {
+	// Browse for HomeWizard devices using the current and legacy services
+	err := browseAll(ctx, o, entries, logger)
+	<-done
+
+	if err != nil {
 		return fmt.Errorf("failed to browse for HomeWizard devices: %w", err)
 	}
 
-	<-ctx.Done()
 	return nil
 }
 
-// extractProductType parses TXT records to find the product_type field
-// TXT records are in format "key=value", e.g., "product_type=HWE-P1"
-func extractProductType(txtRecords []string) string {
-	for _, txt := range txtRecords {
-		if key, value, found := strings.Cut(txt, "="); found && key == "product_type" {
-			return value
-		}
+// parseEntry converts an mDNS service entry seen on iface into a discovered device
+// Returns false if the entry is not a supported HomeWizard device
+func parseEntry(entry *zeroconf.ServiceEntry, service, iface string, o options, logger *log.Logger) (DiscoveredDevice, bool) {
+	// Log raw DNS record details
+	logger.Printf("mDNS entry for %s on %s: Instance=%s, HostName=%s, Port=%d, AddrIPv4=%v, AddrIPv6=%v, Text=%v",
+		service, iface, entry.Instance, entry.HostName, entry.Port, entry.AddrIPv4, entry.AddrIPv6, entry.Text)
+
+	// Extract product_type from TXT records
+	txt := parseTXT(entry.Text)
+	productType := txt.ProductType
+	if productType == "" {
+		logger.Printf("skipping device %s: no product_type in TXT records", entry.Instance)
+		return DiscoveredDevice{}, false
+	}
+
+	// Determine device type from product_type TXT record
+	product, ok := device.LookupProduct(productType)
+	if !ok {
+		// Skip unknown product types
+		logger.Printf("skipping device %s: unknown product_type=%s", entry.Instance, productType)
+		return DiscoveredDevice{}, false
+	}
+
+	// Only consider addresses of the selected IP families
+	var ipv4Addrs, ipv6Addrs []net.IP
+	if o.allowsIPv4() {
+		ipv4Addrs = entry.AddrIPv4
+	}
+	if o.allowsIPv6() {
+		ipv6Addrs = entry.AddrIPv6
 	}
-	return ""
+
+	// Resolve the best hostname or IP address to use
+	host := resolveHost(entry.HostName, ipv4Addrs, ipv6Addrs, iface, logger)
+
+	addrs := make([]string, 0, len(ipv4Addrs)+len(ipv6Addrs))
+	for _, ip := range slices.Concat(ipv4Addrs, ipv6Addrs) {
+		addrs = append(addrs, ip.String())
+	}
+
+	// Classify how the device can be used
+	status := StatusReady
+	switch {
+	case !txt.APIEnabled:
+		status = StatusAPIDisabled
+	case service == serviceLegacy:
+		status = StatusV1Only
+	}
+
+	device := DiscoveredDevice{
+		Status:      status,
+		Instance:    entry.Instance,
+		Serial:      txt.Serial,
+		Host:        host,
+		Addresses:   addrs,
+		Interfaces:  []string{iface},
+		Port:        entry.Port,
+		Type:        product.DeviceType,
+		ProductType: txt.ProductType,
+		ProductName: txt.ProductName,
+		APIVersion:  txt.APIVersion,
+		APIEnabled:  txt.APIEnabled,
+		Path:        txt.Path,
+		TXT:         txt.Raw,
+	}
+
+	logger.Printf("discovered %s (%s): %s at %s:%d on %s", product.DeviceType, status, entry.Instance, host, entry.Port, iface)
+
+	return device, true
 }
 
 // resolveHost attempts to find a resolvable hostname or IP address
-// Tries: hostname with .local, hostname without .local, then falls back to IPv4 address
-func resolveHost(hostname string, ipv4Addrs []net.IP, log *log.Logger) string {
+// Tries: hostname with .local, hostname without .local, then falls back to IPv4 address,
+// global IPv6 address and finally link-local IPv6 address scoped to the interface it was seen on
+func resolveHost(hostname string, ipv4Addrs, ipv6Addrs []net.IP, iface string, log *log.Logger) string {
 	// Remove trailing dot if present
 	hostname = strings.TrimSuffix(hostname, ".")
 
@@ -124,8 +294,15 @@ func resolveHost(hostname string, ipv4Addrs []net.IP, log *log.Logger) string {
 		return ip
 	}
 
+	// Fall back to IPv6 address, preferring global over link-local addresses
+	if ip := preferredIPv6(ipv6Addrs); ip != nil {
+		host := hostForIPv6(ip, iface)
+		log.Printf("hostname %s not resolvable and no IPv4 address, using IPv6 address %s", hostname, host)
+		return host
+	}
+
 	// Last resort: return original hostname
-	log.Printf("hostname %s not resolvable and no IPv4 address, using original hostname", hostname)
+	log.Printf("hostname %s not resolvable and no IP address, using original hostname", hostname)
 	return hostnameWithoutLocal
 }
 
@@ -142,3 +319,26 @@ func tryResolve(hostname string, logger *log.Logger) bool {
 	}
 	return true
 }
+
+// preferredIPv6 returns the first global unicast IPv6 address, or the first link-local address
+func preferredIPv6(addrs []net.IP) net.IP {
+	var linkLocal net.IP
+	for _, ip := range addrs {
+		switch {
+		case ip.IsGlobalUnicast():
+			return ip
+		case ip.IsLinkLocalUnicast() && linkLocal == nil:
+			linkLocal = ip
+		}
+	}
+	return linkLocal
+}
+
+// hostForIPv6 formats an IPv6 address for use as URL host
+// Link-local addresses need the interface as zone, which is escaped as %25 in URLs
+func hostForIPv6(ip net.IP, iface string) string {
+	if ip.IsLinkLocalUnicast() && iface != "" {
+		return "[" + ip.String() + "%25" + iface + "]"
+	}
+	return "[" + ip.String() + "]"
+}
@@ -0,0 +1,61 @@
+package discovery
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestParseTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  []string
+		want txtRecord // Without Raw
+		raw  map[string]string
+	}{
+		{
+			name: "v2",
+			txt:  []string{"product_type=HWE-P1", "product_name=P1 meter", "serial=5c2fafaabbcc", "api_version=2.0.0"},
+			want: txtRecord{ProductType: "HWE-P1", ProductName: "P1 meter", Serial: "5c2fafaabbcc", APIVersion: "2.0.0", APIEnabled: true},
+			raw:  map[string]string{"product_type": "HWE-P1", "product_name": "P1 meter", "serial": "5c2fafaabbcc", "api_version": "2.0.0"},
+		},
+		{
+			name: "legacy api disabled",
+			txt:  []string{"product_type=HWE-SKT", "api_enabled=0", "path=/api/v1"},
+			want: txtRecord{ProductType: "HWE-SKT", Path: "/api/v1"},
+			raw:  map[string]string{"product_type": "HWE-SKT", "api_enabled": "0", "path": "/api/v1"},
+		},
+		{
+			name: "legacy api enabled",
+			txt:  []string{"product_type=HWE-SKT", "api_enabled=true"},
+			want: txtRecord{ProductType: "HWE-SKT", APIEnabled: true},
+			raw:  map[string]string{"product_type": "HWE-SKT", "api_enabled": "true"},
+		},
+		{
+			name: "malformed records",
+			txt:  []string{"product_name=a=b", "flag", "", "vendor="},
+			want: txtRecord{ProductName: "a=b", APIEnabled: true},
+			raw:  map[string]string{"product_name": "a=b", "vendor": ""},
+		},
+		{
+			name: "empty",
+			want: txtRecord{APIEnabled: true},
+			raw:  map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseTXT(tc.txt)
+
+			if !maps.Equal(got.Raw, tc.raw) {
+				t.Errorf("raw = %v, want %v", got.Raw, tc.raw)
+			}
+
+			got.Raw = nil
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/libp2p/zeroconf/v2"
+)
+
+// IPFamily selects the IP address families used for discovery
+type IPFamily string
+
+const (
+	IPFamilyAny IPFamily = "any"
+	IPFamilyV4  IPFamily = "ipv4"
+	IPFamilyV6  IPFamily = "ipv6"
+)
+
+// options configures discovery
+type options struct {
+	interfaces []string
+	family     IPFamily
+}
+
+// Option configures discovery
+type Option func(*options)
+
+// WithInterfaces restricts discovery to the named network interfaces
+// By default all interfaces that are up and support multicast are used
+func WithInterfaces(names ...string) Option {
+	return func(o *options) {
+		o.interfaces = names
+	}
+}
+
+// WithIPFamily restricts discovery and the reported addresses to an IP family
+func WithIPFamily(family IPFamily) Option {
+	return func(o *options) {
+		o.family = family
+	}
+}
+
+// applyOptions returns the options with defaults applied
+func applyOptions(opts ...Option) options {
+	o := options{
+		family: IPFamilyAny,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// allowsIPv4 returns true if IPv4 addresses may be used
+func (o options) allowsIPv4() bool {
+	return o.family != IPFamilyV6
+}
+
+// allowsIPv6 returns true if IPv6 addresses may be used
+func (o options) allowsIPv6() bool {
+	return o.family != IPFamilyV4
+}
+
+// ipTraffic returns the zeroconf IP traffic selection for the configured family
+func (o options) ipTraffic() zeroconf.IPType {
+	switch o.family {
+	case IPFamilyV4:
+		return zeroconf.IPv4
+	case IPFamilyV6:
+		return zeroconf.IPv6
+	default:
+		return zeroconf.IPv4AndIPv6
+	}
+}
+
+// netInterfaces resolves the interfaces to browse on
+func (o options) netInterfaces() ([]net.Interface, error) {
+	if len(o.interfaces) > 0 {
+		res := make([]net.Interface, 0, len(o.interfaces))
+		for _, name := range o.interfaces {
+			iface, err := net.InterfaceByName(name)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", name, err)
+			}
+			res = append(res, *iface)
+		}
+		return res, nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]net.Interface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0 && iface.Flags&net.FlagLoopback == 0 {
+			res = append(res, iface)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, errors.New("no multicast capable network interface found")
+	}
+
+	return res, nil
+}
+
+// browsedEntry is an mDNS service entry together with the service and interface it was seen on
+type browsedEntry struct {
+	entry   *zeroconf.ServiceEntry
+	service string
+	iface   string
+}
+
+// browseAll browses for the current and the legacy HomeWizard service
+// Entries are sent to out, which is closed when done.
+// Blocks until the context is cancelled, returns an error only if browsing the current service failed.
+func browseAll(ctx context.Context, o options, out chan<- browsedEntry, logger *log.Logger) error {
+	defer close(out)
+
+	legacyDone := make(chan struct{})
+	go func() {
+		defer close(legacyDone)
+		// Legacy devices are best-effort, failures are logged by browse
+		_ = browse(ctx, serviceLegacy, o, out, logger)
+	}()
+
+	err := browse(ctx, serviceV2, o, out, logger)
+	<-legacyDone
+
+	return err
+}
+
+// browse runs a zeroconf browse for the service on each interface separately, so entries can be
+// attributed to the interface they were seen on. Entries are sent to out.
+// Blocks until the context is cancelled, returns an error only if browsing failed on all interfaces.
+func browse(ctx context.Context, service string, o options, out chan<- browsedEntry, logger *log.Logger) error {
+	ifaces, err := o.netInterfaces()
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		failed int
+	)
+
+	for _, iface := range ifaces {
+		entries := make(chan *zeroconf.ServiceEntry, 10)
+		stopped := make(chan struct{})
+
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			forward(ctx, entries, stopped, out, service, iface.Name)
+		}()
+
+		go func() {
+			defer wg.Done()
+			defer close(stopped)
+
+			// The entries channel is closed by zeroconf when done, but not if the client fails to start
+			err := zeroconf.Browse(ctx, service, "local.", entries,
+				zeroconf.SelectIfaces([]net.Interface{iface}), zeroconf.SelectIPTraffic(o.ipTraffic()))
+			if err != nil {
+				logger.Printf("failed to browse for %s on %s: %v", service, iface.Name, err)
+
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+				failed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if failed == len(ifaces) {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// forward sends the entries seen on iface to out until browsing stopped
+// Once the context is cancelled the receiver may be gone, so entries are drained and dropped until zeroconf shuts down.
+func forward(ctx context.Context, entries <-chan *zeroconf.ServiceEntry, stopped <-chan struct{}, out chan<- browsedEntry, service, iface string) {
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			select {
+			case out <- browsedEntry{entry: entry, service: service, iface: iface}:
+			case <-ctx.Done():
+			}
+		case <-stopped:
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/zeroconf/v2"
+)
+
+func TestForward(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	stopped := make(chan struct{})
+	out := make(chan browsedEntry, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		forward(ctx, entries, stopped, out, serviceV2, "eth0")
+	}()
+
+	entries <- &zeroconf.ServiceEntry{}
+	if e := <-out; e.service != serviceV2 || e.iface != "eth0" {
+		t.Errorf("entry = %+v", e)
+	}
+
+	// Nobody receives anymore, forwarding must neither block nor stop draining zeroconf
+	cancel()
+	for range 20 {
+		select {
+		case entries <- &zeroconf.ServiceEntry{}:
+		case <-time.After(time.Second):
+			t.Fatal("entries not drained after cancel")
+		}
+	}
+
+	close(stopped)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("forward did not return after browsing stopped")
+	}
+}
@@ -0,0 +1,249 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// Subnet scan defaults
+const (
+	DefaultScanConcurrency  = 32
+	DefaultScanRate         = 50 // probes per second
+	DefaultScanProbeTimeout = 2 * time.Second
+
+	maxScanHosts = 1 << 16 // Largest subnet that may be scanned (/16)
+	maxScanRate  = 1000    // probes per second, higher rates are clamped
+)
+
+// scanOptions configures a subnet scan
+type scanOptions struct {
+	concurrency int
+	rate        int
+	timeout     time.Duration
+}
+
+// ScanOption configures a subnet scan
+type ScanOption func(*scanOptions)
+
+// WithConcurrency sets the maximum number of simultaneous probes
+func WithConcurrency(n int) ScanOption {
+	return func(o *scanOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithRate sets the maximum number of probes started per second, at most 1000
+func WithRate(perSecond int) ScanOption {
+	return func(o *scanOptions) {
+		o.rate = perSecond
+	}
+}
+
+// WithProbeTimeout sets the timeout of a single probe
+func WithProbeTimeout(timeout time.Duration) ScanOption {
+	return func(o *scanOptions) {
+		o.timeout = timeout
+	}
+}
+
+// ScanSubnet probes every address of an IPv4 CIDR range for HomeWizard devices over HTTPS,
+// falling back to HTTP to find devices with v1 firmware. Use this when multicast is blocked, e.g. with devices on a separate VLAN.
+// Calls onDevice for each discovered device. Returns when all addresses are probed, or with the context's error
+// if it is cancelled before. Devices found until then have been reported.
+func ScanSubnet(ctx context.Context, cidr string, onDevice func(DiscoveredDevice), opts ...ScanOption) error {
+	logger := log.Default()
+
+	o := scanOptions{
+		concurrency: DefaultScanConcurrency,
+		rate:        DefaultScanRate,
+		timeout:     DefaultScanProbeTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency <= 0 || o.rate <= 0 || o.timeout <= 0 {
+		return errors.New("invalid scan options: concurrency, rate and timeout must be positive")
+	}
+
+	addrs, err := subnetHosts(cidr)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("starting subnet scan of %s (%d hosts)", cidr, len(addrs))
+
+	client := device.NewHTTPClient(o.timeout)
+
+	ticker := time.NewTicker(time.Second / time.Duration(min(o.rate, maxScanRate)))
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, o.concurrency)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex // Serializes onDevice calls
+	)
+
+feed:
+	for _, addr := range addrs {
+		select {
+		case <-ctx.Done():
+			break feed
+		case <-ticker.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break feed
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			device, ok := probe(ctx, client, addr.String(), logger)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			onDevice(device)
+		}()
+	}
+
+	// Wait for the running probes, those cancelled with the context are incomplete
+	wg.Wait()
+	return ctx.Err()
+}
+
+// probe checks whether a HomeWizard device answers on the host
+// Hosts refusing HTTPS are probed over HTTP, where devices with v1 firmware answer. Unreachable hosts are not probed again.
+func probe(ctx context.Context, client *http.Client, host string, logger *log.Logger) (DiscoveredDevice, bool) {
+	port := 443
+	info, err := probeInfo(ctx, client, fmt.Sprintf("https://%s/api", host))
+	if err != nil && !isTimeout(err) && ctx.Err() == nil {
+		port = 80
+		info, err = probeInfo(ctx, client, fmt.Sprintf("http://%s/api", host))
+	}
+	if err != nil {
+		return DiscoveredDevice{}, false
+	}
+
+	product, ok := device.LookupProduct(info.ProductType)
+	if !ok {
+		logger.Printf("skipping device at %s: unknown product_type=%s", host, info.ProductType)
+		return DiscoveredDevice{}, false
+	}
+
+	// Devices without HTTPS can't be paired until their firmware is updated
+	status := StatusReady
+	if port == 80 || strings.HasPrefix(info.APIVersion, "v1") {
+		status = StatusV1Only
+	}
+
+	discovered := DiscoveredDevice{
+		Status:      status,
+		Instance:    scanInstance(info),
+		Serial:      info.Serial,
+		Host:        host,
+		Addresses:   []string{host},
+		Port:        port,
+		Type:        product.DeviceType,
+		ProductType: info.ProductType,
+		ProductName: info.ProductName,
+		APIVersion:  info.APIVersion,
+		APIEnabled:  true,
+	}
+
+	logger.Printf("discovered %s: %s at %s (api %s)", product.DeviceType, discovered.Instance, host, info.APIVersion)
+
+	return discovered, true
+}
+
+// probeInfo requests the device information, which is returned without authentication
+func probeInfo(ctx context.Context, client *http.Client, uri string) (device.DeviceInfo, error) {
+	var info device.DeviceInfo
+
+	req, err := device.NewAPIRequest(ctx, http.MethodGet, uri, "", nil)
+	if err != nil {
+		return info, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, err
+	}
+	if info.ProductType == "" {
+		return info, errors.New("not a HomeWizard device")
+	}
+
+	return info, nil
+}
+
+// isTimeout returns true if the host did not answer in time
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// scanInstance derives an instance name similar to the mDNS announcement, e.g. "P1 meter-aabbcc"
+func scanInstance(info device.DeviceInfo) string {
+	serial := info.Serial
+	if len(serial) > 6 {
+		serial = serial[len(serial)-6:]
+	}
+	return info.ProductName + "-" + serial
+}
+
+// subnetHosts returns all host addresses of an IPv4 CIDR range
+// Network and broadcast addresses are excluded for prefixes shorter than /31
+func subnetHosts(cidr string) ([]netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet %s: %w", cidr, err)
+	}
+
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("invalid subnet %s: only IPv4 ranges can be scanned", cidr)
+	}
+
+	prefix = prefix.Masked()
+	size := 1 << (net.IPv4len*8 - prefix.Bits())
+	if size > maxScanHosts {
+		return nil, fmt.Errorf("subnet %s too large: at most /16 can be scanned", cidr)
+	}
+
+	res := make([]netip.Addr, 0, size)
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		res = append(res, addr)
+	}
+
+	if prefix.Bits() < 31 {
+		res = res[1 : len(res)-1]
+	}
+
+	return res, nil
+}
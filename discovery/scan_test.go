@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+func TestSubnetHosts(t *testing.T) {
+	tests := []struct {
+		cidr        string
+		count       int
+		first, last string
+		err         bool
+	}{
+		{cidr: "192.168.1.0/24", count: 254, first: "192.168.1.1", last: "192.168.1.254"},
+		{cidr: "192.168.1.77/24", count: 254, first: "192.168.1.1", last: "192.168.1.254"},
+		{cidr: "10.0.0.0/30", count: 2, first: "10.0.0.1", last: "10.0.0.2"},
+		{cidr: "10.0.0.0/31", count: 2, first: "10.0.0.0", last: "10.0.0.1"},
+		{cidr: "10.0.0.5/32", count: 1, first: "10.0.0.5", last: "10.0.0.5"},
+		{cidr: "10.0.0.0/16", count: 65534, first: "10.0.0.1", last: "10.0.255.254"},
+		{cidr: "10.0.0.0/15", err: true},
+		{cidr: "fd00::/120", err: true},
+		{cidr: "192.168.1.0", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.cidr, func(t *testing.T) {
+			addrs, err := subnetHosts(tc.cidr)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(addrs) != tc.count || addrs[0].String() != tc.first || addrs[len(addrs)-1].String() != tc.last {
+				t.Errorf("got %d hosts %s-%s, want %d hosts %s-%s", len(addrs), addrs[0], addrs[len(addrs)-1], tc.count, tc.first, tc.last)
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	v2 := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"product_type":"HWE-P1","product_name":"P1 meter","serial":"5c2fafaabbcc","api_version":"2.0.0"}`))
+	}))
+	defer v2.Close()
+
+	v1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"product_type":"HWE-SKT","product_name":"Energy Socket","serial":"3c39e7aabbcc","api_version":"v1"}`))
+	}))
+	defer v1.Close()
+
+	other := httptest.NewTLSServer(http.NotFoundHandler())
+	defer other.Close()
+
+	tests := []struct {
+		name   string
+		host   string
+		found  bool
+		status Status
+		port   int
+	}{
+		{"v2", v2.Listener.Addr().String(), true, StatusReady, 443},
+		{"v1 over http", v1.Listener.Addr().String(), true, StatusV1Only, 80},
+		{"not a device", other.Listener.Addr().String(), false, "", 0},
+	}
+
+	client := device.NewHTTPClient(time.Second)
+	logger := log.New(io.Discard, "", 0)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, ok := probe(context.Background(), client, tc.host, logger)
+			if ok != tc.found {
+				t.Fatalf("found = %v, want %v", ok, tc.found)
+			}
+			if ok && (d.Status != tc.status || d.Port != tc.port || d.Host != tc.host) {
+				t.Errorf("device = %+v, want status %s on port %d", d, tc.status, tc.port)
+			}
+		})
+	}
+}
+
+func TestScanSubnetCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := ScanSubnet(ctx, "192.0.2.0/24", func(DiscoveredDevice) {}, WithRate(1<<40))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	if err := ScanSubnet(ctx, "192.0.2.0/24", nil, WithRate(0)); err == nil || !strings.Contains(err.Error(), "invalid scan options") {
+		t.Errorf("err = %v, want invalid scan options", err)
+	}
+}
@@ -0,0 +1,230 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRemoveGrace is how long a device may be silent after its mDNS TTL expired before it is removed
+	// zeroconf re-queries at most every 60 seconds, so a shorter grace period would cause spurious removals
+	DefaultRemoveGrace = 90 * time.Second
+
+	expiryCheckInterval = 10 * time.Second
+)
+
+// EventType identifies what changed in the watcher inventory
+type EventType string
+
+const (
+	EventAdded   EventType = "added"   // Device seen for the first time
+	EventUpdated EventType = "updated" // Device address or status changed
+	EventRemoved EventType = "removed" // Device TTL expired
+)
+
+// Event is emitted by the watcher when the inventory changes
+type Event struct {
+	Type   EventType
+	Device DiscoveredDevice
+}
+
+// watchedDevice is an inventory entry of the watcher
+type watchedDevice struct {
+	device DiscoveredDevice
+	expiry time.Time
+}
+
+// Watcher continuously browses for HomeWizard devices and keeps an inventory keyed by serial
+type Watcher struct {
+	mu      sync.Mutex
+	log     *log.Logger
+	opts    options
+	onEvent func(Event)
+	grace   time.Duration
+	devices map[string]*watchedDevice
+}
+
+// NewWatcher creates a discovery watcher, onEvent may be nil if only the inventory is used
+func NewWatcher(onEvent func(Event), opts ...Option) *Watcher {
+	return &Watcher{
+		log:     log.Default(),
+		opts:    applyOptions(opts...),
+		onEvent: onEvent,
+		grace:   DefaultRemoveGrace,
+		devices: make(map[string]*watchedDevice),
+	}
+}
+
+// SetRemoveGrace sets how long a device may be silent after its TTL expired before it is removed
+// Should be called before Run
+func (w *Watcher) SetRemoveGrace(grace time.Duration) {
+	w.grace = grace
+}
+
+// Run browses for devices until the context is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	w.log.Printf("starting mDNS watcher for %s and %s", serviceV2, serviceLegacy)
+
+	entries := make(chan browsedEntry, 10)
+	errC := make(chan error, 1)
+
+	// The entries channel will be closed by browse when done
+	go func() {
+		errC <- browseAll(ctx, w.opts, entries, w.log)
+	}()
+
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				entries = nil
+				continue
+			}
+			if device, ok := parseEntry(e.entry, e.service, e.iface, w.opts, w.log); ok {
+				w.update(device, e.entry.Expiry)
+			}
+
+		case now := <-ticker.C:
+			w.expire(now)
+
+		case err := <-errC:
+			if err != nil {
+				return fmt.Errorf("failed to browse for HomeWizard devices: %w", err)
+			}
+			errC = nil
+
+		case <-ctx.Done():
+			// Browsing stops with the context, wait for it so no goroutines outlive Run
+			if errC != nil {
+				<-errC
+			}
+			return nil
+		}
+	}
+}
+
+// Devices returns the current inventory sorted by serial
+func (w *Watcher) Devices() []DiscoveredDevice {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	res := make([]DiscoveredDevice, 0, len(w.devices))
+	for _, d := range w.devices {
+		res = append(res, d.device)
+	}
+
+	slices.SortFunc(res, func(a, b DiscoveredDevice) int {
+		return strings.Compare(deviceKey(a), deviceKey(b))
+	})
+
+	return res
+}
+
+// Device returns the device with the given serial from the inventory
+func (w *Watcher) Device(serial string) (DiscoveredDevice, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	d, ok := w.devices[serial]
+	if !ok {
+		return DiscoveredDevice{}, false
+	}
+	return d.device, true
+}
+
+// update adds or refreshes a device in the inventory
+func (w *Watcher) update(device DiscoveredDevice, expiry time.Time) {
+	key := deviceKey(device)
+
+	w.mu.Lock()
+
+	var event *Event
+	if d, ok := w.devices[key]; !ok {
+		w.devices[key] = &watchedDevice{device: device, expiry: expiry}
+		event = &Event{Type: EventAdded, Device: device}
+	} else {
+		known := d.device
+
+		// Legacy announcements of a v2 device only contribute addresses
+		if device.Status.rank() > known.Status.rank() {
+			d.device.merge(device)
+			w.mu.Unlock()
+			return
+		}
+
+		// Keep the previous host while the device still announces it, e.g. when seen on another interface
+		if known.Host != device.Host && slices.Contains(device.Addresses, hostAddress(known.Host)) {
+			device.Host = known.Host
+		}
+
+		changed := known.Host != device.Host || known.Port != device.Port || known.Status != device.Status
+
+		// Keep addresses and interfaces seen on other interfaces
+		d.device = device
+		d.device.Addresses = slices.Clone(known.Addresses)
+		d.device.Interfaces = slices.Clone(known.Interfaces)
+		d.device.merge(device)
+		if expiry.After(d.expiry) {
+			d.expiry = expiry
+		}
+		if changed {
+			event = &Event{Type: EventUpdated, Device: d.device}
+		}
+	}
+
+	w.mu.Unlock()
+
+	if event != nil {
+		w.log.Printf("%s %s: %s at %s:%d", event.Type, device.Type, key, device.Host, device.Port)
+		w.emit(*event)
+	}
+}
+
+// expire removes devices whose TTL expired longer than the grace period ago
+func (w *Watcher) expire(now time.Time) {
+	var removed []DiscoveredDevice
+
+	w.mu.Lock()
+	for key, d := range w.devices {
+		if now.After(d.expiry.Add(w.grace)) {
+			delete(w.devices, key)
+			removed = append(removed, d.device)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, device := range removed {
+		w.log.Printf("removed %s: %s", device.Type, deviceKey(device))
+		w.emit(Event{Type: EventRemoved, Device: device})
+	}
+}
+
+// emit passes an event to the callback outside the lock
+func (w *Watcher) emit(event Event) {
+	if w.onEvent != nil {
+		w.onEvent(event)
+	}
+}
+
+// deviceKey returns the inventory key of a device, falling back to the instance name without serial
+func deviceKey(device DiscoveredDevice) string {
+	if device.Serial != "" {
+		return device.Serial
+	}
+	return device.Instance
+}
+
+// hostAddress strips URL brackets and zone from an IPv6 host
+func hostAddress(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	addr, _, _ := strings.Cut(host, "%25")
+	return addr
+}
@@ -0,0 +1,33 @@
+// Package atomicfile replaces files atomically, so readers never see a partially written file
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write writes the data to a temporary file next to path and renames it over path
+// The file gets the given permissions, the temporary file is removed on failure.
+func Write(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,405 @@
+package pairing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/internal/atomicfile"
+	"go.yaml.in/yaml/v4"
+)
+
+// MeterType is the evcc meter type of HomeWizard devices
+const MeterType = "homewizard-v2"
+
+// ConfigFormat is the output format of the generated configuration
+type ConfigFormat string
+
+const (
+	ConfigFormatYAML ConfigFormat = "yaml"
+	ConfigFormatJSON ConfigFormat = "json"
+)
+
+// ParseConfigFormat validates and converts a string to a ConfigFormat
+func ParseConfigFormat(s string) (ConfigFormat, error) {
+	switch f := ConfigFormat(s); f {
+	case ConfigFormatYAML, ConfigFormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid config format: %s", s)
+	}
+}
+
+// Config is the evcc configuration of the paired meters
+type Config struct {
+	Meters []MeterConfig `json:"meters" yaml:"meters"`
+	Site   *SiteConfig   `json:"site,omitempty" yaml:"site,omitempty"`
+}
+
+// MeterConfig is an evcc meter of type homewizard-v2
+type MeterConfig struct {
+	Name  string        `json:"name" yaml:"name"`
+	Type  string        `json:"type" yaml:"type"`
+	Usage adapter.Usage `json:"usage" yaml:"usage"`
+	Host  string        `json:"host" yaml:"host"`
+	Token string        `json:"token" yaml:"token"`
+}
+
+// SiteConfig references the meters of the site
+// Charge meters are referenced by loadpoints instead
+type SiteConfig struct {
+	Title  string          `json:"title,omitempty" yaml:"title,omitempty"`
+	Meters SiteMeterConfig `json:"meters" yaml:"meters"`
+}
+
+// SiteMeterConfig assigns the meters to the site
+type SiteMeterConfig struct {
+	Grid    string   `json:"grid,omitempty" yaml:"grid,omitempty"`
+	PV      []string `json:"pv,omitempty" yaml:"pv,omitempty"`
+	Battery []string `json:"battery,omitempty" yaml:"battery,omitempty"`
+	Aux     []string `json:"aux,omitempty" yaml:"aux,omitempty"`
+}
+
+// NewConfig builds the configuration of the meters, devices without usage are skipped
+// With site, a site section referencing the meters is added. Only the first grid meter can be the site's grid meter.
+func NewConfig(meters []Meter, site bool) Config {
+	var res Config
+	for _, m := range meters {
+		if m.Usage == "" {
+			continue
+		}
+
+		res.Meters = append(res.Meters, MeterConfig{
+			Name:  m.Name,
+			Type:  MeterType,
+			Usage: m.Usage,
+			Host:  m.Device.Host,
+			Token: m.Device.Token,
+		})
+	}
+
+	if site {
+		res.Site = newSiteConfig(res.Meters)
+	}
+
+	return res
+}
+
+// newSiteConfig assigns the meters to the site by usage
+func newSiteConfig(meters []MeterConfig) *SiteConfig {
+	var res SiteConfig
+	for _, m := range meters {
+		switch m.Usage {
+		case adapter.UsageGrid:
+			if res.Meters.Grid == "" {
+				res.Meters.Grid = m.Name
+			}
+		case adapter.UsagePV:
+			res.Meters.PV = append(res.Meters.PV, m.Name)
+		case adapter.UsageBattery:
+			res.Meters.Battery = append(res.Meters.Battery, m.Name)
+		case adapter.UsageAux:
+			res.Meters.Aux = append(res.Meters.Aux, m.Name)
+		}
+	}
+	return &res
+}
+
+// Marshal encodes the configuration in the given format
+func (c Config) Marshal(format ConfigFormat) ([]byte, error) {
+	switch format {
+	case ConfigFormatJSON:
+		b, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+
+	case ConfigFormatYAML:
+		return encodeYAML(c, defaultYAMLStyle)
+
+	default:
+		return nil, fmt.Errorf("invalid config format: %s", format)
+	}
+}
+
+// MergeResult lists the meters changed by a merge
+type MergeResult struct {
+	Updated []string // Existing homewizard-v2 meters with updated host and token
+	Added   []string // Meters appended to the meters list
+}
+
+// MergeConfig updates host and token of the meters with matching name in an existing evcc.yaml
+// A homewizard-v2 meter with the same host but a different name is updated instead of adding a duplicate.
+// Meters not found are appended, everything else, including comments and indentation, is preserved.
+// The site section is not changed.
+func MergeConfig(data []byte, c Config) ([]byte, MergeResult, error) {
+	var res MergeResult
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, res, fmt.Errorf("parse config: %w", err)
+	}
+
+	// Empty file
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, res, errors.New("parse config: not a mapping")
+	}
+
+	meters := mappingValue(root, "meters")
+	if meters == nil {
+		meters = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "meters"}, meters)
+	}
+
+	// Empty meters key
+	if meters.Kind == yaml.ScalarNode && meters.Tag == "!!null" {
+		meters.Kind, meters.Tag, meters.Value = yaml.SequenceNode, "", ""
+	}
+
+	if meters.Kind != yaml.SequenceNode {
+		return nil, res, errors.New("parse config: meters is not a list")
+	}
+
+	for _, m := range c.Meters {
+		if existing := findMeter(meters, m); existing != nil {
+			if !isMeterType(existing) {
+				return nil, res, fmt.Errorf("meter %s exists with a different type", m.Name)
+			}
+
+			name := m.Name
+			if n := mappingValue(existing, "name"); n != nil {
+				name = n.Value
+			}
+
+			setMappingValue(existing, "host", m.Host)
+			setMappingValue(existing, "token", m.Token)
+			res.Updated = append(res.Updated, name)
+			continue
+		}
+
+		var node yaml.Node
+		if err := node.Encode(m); err != nil {
+			return nil, res, err
+		}
+
+		meters.Content = append(meters.Content, &node)
+		res.Added = append(res.Added, m.Name)
+	}
+
+	b, err := encodeYAML(&doc, detectYAMLStyle(data))
+	return b, res, err
+}
+
+// MergeConfigFile merges the configuration into an existing evcc.yaml, see MergeConfig
+// The file is replaced atomically and keeps its permissions.
+func MergeConfigFile(path string, c Config) (MergeResult, error) {
+	var res MergeResult
+	err := updateConfigFile(path, func(data []byte) ([]byte, error) {
+		b, r, err := MergeConfig(data, c)
+		res = r
+		return b, err
+	})
+	return res, err
+}
+
+// ReplaceConfigToken replaces the token of the homewizard-v2 meters using oldToken in an existing evcc.yaml
+// Returns the names of the updated meters, everything else, including comments, is preserved.
+func ReplaceConfigToken(data []byte, oldToken, newToken string) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	if doc.Kind == 0 {
+		return data, nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, errors.New("parse config: not a mapping")
+	}
+
+	var res []string
+	if meters := mappingValue(root, "meters"); meters != nil && meters.Kind == yaml.SequenceNode {
+		for _, m := range meters.Content {
+			if m.Kind != yaml.MappingNode {
+				continue
+			}
+			if !isMeterType(m) {
+				continue
+			}
+			if token := mappingValue(m, "token"); token != nil && token.Value == oldToken {
+				token.SetString(newToken)
+				if n := mappingValue(m, "name"); n != nil {
+					res = append(res, n.Value)
+				}
+			}
+		}
+	}
+
+	// Leave the file untouched if there is nothing to replace
+	if len(res) == 0 {
+		return data, nil, nil
+	}
+
+	b, err := encodeYAML(&doc, detectYAMLStyle(data))
+	return b, res, err
+}
+
+// ReplaceConfigTokenFile replaces a token in an existing evcc.yaml, see ReplaceConfigToken
+// The file is replaced atomically and keeps its permissions.
+func ReplaceConfigTokenFile(path, oldToken, newToken string) ([]string, error) {
+	var res []string
+	err := updateConfigFile(path, func(data []byte) ([]byte, error) {
+		b, r, err := ReplaceConfigToken(data, oldToken, newToken)
+		res = r
+		return b, err
+	})
+	return res, err
+}
+
+// updateConfigFile atomically replaces the file with the result of update, keeping its permissions
+func updateConfigFile(path string, update func([]byte) ([]byte, error)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	b, err := update(data)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(path, b, fi.Mode().Perm())
+}
+
+// yamlStyle is the indentation used to encode YAML
+type yamlStyle struct {
+	indent  int
+	compact bool // List items are indented less than mapping keys
+}
+
+// defaultYAMLStyle is the 2 space indentation with compact lists of the evcc templates
+var defaultYAMLStyle = yamlStyle{indent: 2, compact: true}
+
+// detectYAMLStyle detects the indentation of an existing YAML document from the first nested mapping and list
+// Falls back to defaultYAMLStyle for anything that cannot be detected.
+func detectYAMLStyle(data []byte) yamlStyle {
+	mapIndent, seqIndent := -1, -1
+	parent := -1 // Indentation of the previous line opening a block, -1 if none
+
+	for line := range bytes.Lines(data) {
+		trimmed := bytes.TrimLeft(line, " ")
+		content := bytes.TrimSpace(trimmed)
+		if len(content) == 0 || content[0] == '#' {
+			continue
+		}
+		if i := bytes.Index(content, []byte(" #")); i >= 0 {
+			content = bytes.TrimSpace(content[:i])
+		}
+
+		indent := len(line) - len(trimmed)
+		if parent >= 0 {
+			switch {
+			case content[0] == '-' && seqIndent < 0 && indent >= parent:
+				seqIndent = indent - parent
+			case content[0] != '-' && mapIndent < 0 && indent > parent:
+				mapIndent = indent - parent
+			}
+		}
+
+		parent = -1
+		if content[0] != '-' && bytes.HasSuffix(content, []byte(":")) {
+			parent = indent
+		}
+	}
+
+	switch {
+	case mapIndent > 0:
+		return yamlStyle{indent: mapIndent, compact: seqIndent < 0 || seqIndent < mapIndent}
+	case seqIndent > 0:
+		return yamlStyle{indent: seqIndent}
+	default:
+		return defaultYAMLStyle
+	}
+}
+
+// encodeYAML encodes with the given indentation
+func encodeYAML(v any, style yamlStyle) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(style.indent)
+	if style.compact {
+		enc.CompactSeqIndent()
+	}
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// findMeter returns the meter with the name of m from the meters list,
+// or else the homewizard-v2 meter with the same host
+func findMeter(meters *yaml.Node, m MeterConfig) *yaml.Node {
+	var sameHost *yaml.Node
+	for _, n := range meters.Content {
+		if n.Kind != yaml.MappingNode {
+			continue
+		}
+		if name := mappingValue(n, "name"); name != nil && name.Value == m.Name {
+			return n
+		}
+		if host := mappingValue(n, "host"); sameHost == nil && host != nil && host.Value == m.Host && isMeterType(n) {
+			sameHost = n
+		}
+	}
+	return sameHost
+}
+
+// isMeterType returns true if the meter node is of type homewizard-v2
+func isMeterType(m *yaml.Node) bool {
+	t := mappingValue(m, "type")
+	return t != nil && t.Value == MeterType
+}
+
+// mappingValue returns the value node of a key of a mapping node
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets a string value of a mapping node, adding the key if missing
+func setMappingValue(m *yaml.Node, key, value string) {
+	if v := mappingValue(m, key); v != nil {
+		v.SetString(value)
+		return
+	}
+
+	var v yaml.Node
+	v.SetString(value)
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &v)
+}
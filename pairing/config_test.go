@@ -0,0 +1,144 @@
+package pairing
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+)
+
+func TestMergeConfig(t *testing.T) {
+	cfg := Config{Meters: []MeterConfig{
+		{Name: "grid", Type: MeterType, Usage: adapter.UsageGrid, Host: "192.168.1.10", Token: "new"},
+	}}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		updated []string
+		added   []string
+		err     bool
+	}{
+		{
+			name:  "empty file",
+			data:  "",
+			want:  "meters:\n- name: grid\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: new\n",
+			added: []string{"grid"},
+		},
+		{
+			name:  "null meters",
+			data:  "meters:\n",
+			want:  "meters:\n- name: grid\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: new\n",
+			added: []string{"grid"},
+		},
+		{
+			name:    "same name",
+			data:    "# evcc\nmeters:\n- name: grid # P1\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.5\n  token: old\n",
+			want:    "# evcc\nmeters:\n- name: grid # P1\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: new\n",
+			updated: []string{"grid"},
+		},
+		{
+			name:    "same host",
+			data:    "meters:\n- name: p1\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: old\n",
+			want:    "meters:\n- name: p1\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: new\n",
+			updated: []string{"p1"},
+		},
+		{
+			name:  "same host other type",
+			data:  "meters:\n- name: p1\n  type: template\n  host: 192.168.1.10\n",
+			want:  "meters:\n- name: p1\n  type: template\n  host: 192.168.1.10\n- name: grid\n  type: homewizard-v2\n  usage: grid\n  host: 192.168.1.10\n  token: new\n",
+			added: []string{"grid"},
+		},
+		{
+			name:    "indentation kept",
+			data:    "meters:\n    - name: grid\n      type: homewizard-v2\n      host: 192.168.1.5\n      token: old\nsite:\n    title: Home\n",
+			want:    "meters:\n    - name: grid\n      type: homewizard-v2\n      host: 192.168.1.10\n      token: new\nsite:\n    title: Home\n",
+			updated: []string{"grid"},
+		},
+		{
+			name:    "compact indentation kept",
+			data:    "site:\n    title: Home\nmeters:\n  - name: grid\n    type: homewizard-v2\n    host: 192.168.1.5\n    token: old\n",
+			want:    "site:\n    title: Home\nmeters:\n  - name: grid\n    type: homewizard-v2\n    host: 192.168.1.10\n    token: new\n",
+			updated: []string{"grid"},
+		},
+		{
+			name: "same name other type",
+			data: "meters:\n- name: grid\n  type: template\n",
+			err:  true,
+		},
+		{
+			name: "meters not a list",
+			data: "meters: grid\n",
+			err:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, res, err := MergeConfig([]byte(tc.data), cfg)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != tc.want {
+				t.Errorf("config:\n%s\nwant:\n%s", b, tc.want)
+			}
+			if !slices.Equal(res.Updated, tc.updated) || !slices.Equal(res.Added, tc.added) {
+				t.Errorf("result = %+v, want updated %v, added %v", res, tc.updated, tc.added)
+			}
+		})
+	}
+}
+
+func TestReplaceConfigToken(t *testing.T) {
+	const data = "meters:\n  - name: grid\n    type: homewizard-v2\n    token: old\n  - name: pv\n    type: homewizard-v2\n    token: other\n  - name: aux\n    type: template\n    token: old\n"
+
+	tests := []struct {
+		name     string
+		data     string
+		oldToken string
+		want     string
+		updated  []string
+	}{
+		{
+			name:     "replaced",
+			data:     data,
+			oldToken: "old",
+			want:     "meters:\n  - name: grid\n    type: homewizard-v2\n    token: new\n  - name: pv\n    type: homewizard-v2\n    token: other\n  - name: aux\n    type: template\n    token: old\n",
+			updated:  []string{"grid"},
+		},
+		{
+			name:     "not found",
+			data:     data,
+			oldToken: "unknown",
+			want:     data,
+		},
+		{
+			name:     "empty file",
+			oldToken: "old",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, updated, err := ReplaceConfigToken([]byte(tc.data), tc.oldToken, "new")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != tc.want {
+				t.Errorf("config:\n%s\nwant:\n%s", b, tc.want)
+			}
+			if !slices.Equal(updated, tc.updated) {
+				t.Errorf("updated = %v, want %v", updated, tc.updated)
+			}
+		})
+	}
+}
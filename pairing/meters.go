@@ -0,0 +1,83 @@
+package pairing
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// Meter assigns an evcc meter name and usage to a paired device
+type Meter struct {
+	Name   string
+	Usage  adapter.Usage // Empty for devices that are not evcc meters, e.g. watermeters
+	Device PairedDevice
+}
+
+// DefaultUsage returns the default evcc usage of a product type, falling back to the device type
+// Returns an empty usage for devices that are not evcc meters
+func DefaultUsage(productType string, deviceType device.DeviceType) adapter.Usage {
+	if p, ok := productFor(productType, deviceType); ok {
+		return adapter.Usage(p.Usage)
+	}
+	return adapter.UsageGrid // Unknown type - assume it is the P1 meter
+}
+
+// DefaultMeters assigns each device the default usage of its product type and a unique name
+// Meters are ordered by usage, multiple meters of the same usage are numbered, e.g. grid, grid2
+func DefaultMeters(devices []PairedDevice) []Meter {
+	usages := []adapter.Usage{adapter.UsageGrid, adapter.UsagePV, adapter.UsageCharge, adapter.UsageBattery, adapter.UsageAux}
+
+	byUsage := make(map[adapter.Usage][]PairedDevice)
+	for _, d := range devices {
+		usage := DefaultUsage(d.ProductType, d.Type)
+		if !slices.Contains(usages, usage) {
+			usages = append(usages, usage)
+		}
+		byUsage[usage] = append(byUsage[usage], d)
+	}
+
+	var (
+		res   []Meter
+		names []string
+	)
+
+	for _, usage := range usages {
+		for _, d := range byUsage[usage] {
+			var name string
+			if usage != "" {
+				name = UniqueName(string(usage), names)
+				names = append(names, name)
+			}
+			res = append(res, Meter{Name: name, Usage: usage, Device: d})
+		}
+	}
+
+	return res
+}
+
+// UniqueName returns the name if not taken, otherwise the name with the lowest free number, e.g. pv2
+func UniqueName(name string, taken []string) string {
+	res := name
+	for i := 2; slices.Contains(taken, res); i++ {
+		res = fmt.Sprintf("%s%d", name, i)
+	}
+	return res
+}
+
+// productFor looks up the product registration of a device
+// Falls back to the first product of the device type if the product type is unknown
+func productFor(productType string, deviceType device.DeviceType) (device.Product, bool) {
+	if p, ok := device.LookupProduct(productType); ok {
+		return p, true
+	}
+
+	for _, p := range device.Products() {
+		if p.DeviceType == deviceType {
+			return p, true
+		}
+	}
+
+	return device.Product{}, false
+}
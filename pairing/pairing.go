@@ -2,338 +2,354 @@ package pairing
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/evcc-io/evcc/util/request"
 	"github.com/mluiten/evcc-homewizard-v2/device"
 	"github.com/mluiten/evcc-homewizard-v2/discovery"
 )
 
-// PairedDevice represents a device that has been successfully paired
-type PairedDevice struct {
-	Host  string
-	Token string
-	Type  device.DeviceType
-}
-
-// DiscoverAndPairDevices executes the interactive pairing flow
-func DiscoverAndPairDevices(name string, timeout int) error {
-	// Validate name according to HomeWizard API requirements
-	namePattern := regexp.MustCompile(`^[a-zA-Z0-9\-_/\\# ]{1,40}$`)
-	if !namePattern.MatchString(name) {
-		return fmt.Errorf("invalid name: must be 1-40 characters (a-z, A-Z, 0-9, -, _, \\, /, #, spaces)")
-	}
-
-	// Discovery mode
-	fmt.Println("HomeWizard Device Discovery")
-	fmt.Println("===========================")
-	fmt.Println()
-	fmt.Printf("Scanning network (max %ds)...\n", timeout)
-	fmt.Println()
+const (
+	// DefaultQuietPeriod is how long discovery waits for further devices after the last one was found
+	DefaultQuietPeriod = 3 * time.Second
 
-	devices := discoverInteractively(timeout)
+	// PairTimeout is how long pairing waits for the button to be pressed
+	PairTimeout = 3 * time.Minute
 
-	if len(devices) == 0 {
-		return fmt.Errorf("no HomeWizard devices found on network 😞")
-	}
-
-	fmt.Println()
-	fmt.Println("HomeWizard Device Pairing")
-	fmt.Println("=========================")
-	fmt.Println()
-	fmt.Println("Press the button on your devices:")
-	fmt.Println()
+	// MaxAttempts is the number of token requests made while waiting for the button
+	MaxAttempts = int(PairTimeout / attemptInterval)
 
-	// Pair all devices in parallel
-	paired := pairDevicesParallel(devices, name)
+	attemptInterval = 5 * time.Second
+	verifyTimeout   = 30 * time.Second
+)
 
-	// Print configuration
-	printHomeWizardMultiConfig(paired)
+// namePattern are the characters allowed in a HomeWizard API user name
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9\-_/\\# ]{1,40}$`)
 
-	return nil
+// PairedDevice represents a device that has been successfully paired
+type PairedDevice struct {
+	Host            string
+	Token           string
+	Type            device.DeviceType
+	ProductType     string
+	ProductName     string
+	Serial          string
+	FirmwareVersion string
 }
 
-// PairSingleDevice pairs a specific device without discovery
-func PairSingleDevice(host, name string) error {
-	// Validate name according to HomeWizard API requirements
-	namePattern := regexp.MustCompile(`^[a-zA-Z0-9\-_/\\# ]{1,40}$`)
-	if !namePattern.MatchString(name) {
-		return fmt.Errorf("invalid name: must be 1-40 characters (a-z, A-Z, 0-9, -, _, \\, /, #, spaces)")
-	}
+// options configures pairing
+type options struct {
+	store      device.TokenStore
+	format     ConfigFormat
+	site       bool
+	configFile string
+	discover   DiscoverFunc
+}
 
-	host = strings.TrimPrefix(host, "http://")
-	host = strings.TrimPrefix(host, "https://")
+// Option configures pairing
+type Option func(*options)
 
-	fmt.Println("HomeWizard Device Pairing")
-	fmt.Println("=========================")
-	fmt.Println()
-	fmt.Printf("Device: %s\n", host)
-	fmt.Println()
-	fmt.Println("Press the button on your device:")
-	fmt.Println()
-	fmt.Println()
-
-	status := deviceStatus{
-		device: discovery.DiscoveredDevice{
-			Host: host,
+// applyOptions returns the options with defaults applied
+func applyOptions(opts ...Option) options {
+	o := options{
+		format: ConfigFormatYAML,
+		discover: func(ctx context.Context, onEvent func(Event)) ([]discovery.DiscoveredDevice, error) {
+			return Discover(ctx, onEvent)
 		},
-		status: "initializing...",
 	}
-
-	updateStatusLine(0, &status, 1)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-	defer cancel()
-
-	// Pair the device
-	token, err := pairDeviceWithContext(ctx, host, name, func(attempt int) {
-		status.attempt = attempt
-		status.status = fmt.Sprintf("waiting for button press (attempt %d/36)...", attempt)
-		updateStatusLine(0, &status, 1)
-	})
-
-	if err != nil {
-		fmt.Println()
-		return fmt.Errorf("pairing failed: %w", err)
+	for _, opt := range opts {
+		opt(&o)
 	}
-
-	fmt.Println()
-	fmt.Println()
-	fmt.Println("========================================")
-	fmt.Println("Pairing Successful!")
-	fmt.Println("========================================")
-	fmt.Println()
-	fmt.Printf("Token: %s\n", token)
-	fmt.Println()
-
-	return nil
+	return o
 }
 
-type discoverySpinner struct {
-	frames []string
-	idx    int
-	active bool
+// WithTokenStore saves the token of each paired or rotated device in the store, keyed by serial
+func WithTokenStore(store device.TokenStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
 }
 
-func newSpinner() *discoverySpinner {
-	return &discoverySpinner{
-		frames: []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
-		active: true,
+// WithConfigFormat sets the format of the configuration printed by the interactive flow, YAML by default
+func WithConfigFormat(format ConfigFormat) Option {
+	return func(o *options) {
+		o.format = format
 	}
 }
 
-func (s *discoverySpinner) tick() {
-	if s.active {
-		s.idx = (s.idx + 1) % len(s.frames)
-		fmt.Printf("\r%s Searching...", s.frames[s.idx])
+// WithSite adds a site section referencing the meters to the configuration printed by the interactive flow
+func WithSite() Option {
+	return func(o *options) {
+		o.site = true
 	}
 }
 
-func (s *discoverySpinner) clear() {
-	fmt.Print("\r\033[K")
+// WithConfigFile makes the interactive flow merge the meters into an existing evcc.yaml instead of printing them
+// RotateToken replaces rotated tokens in it.
+func WithConfigFile(path string) Option {
+	return func(o *options) {
+		o.configFile = path
+	}
 }
 
-func (s *discoverySpinner) stop() {
-	s.clear()
-	s.active = false
-}
+// DiscoverFunc finds devices to pair, reporting each one as EventDiscovered, see Discover
+type DiscoverFunc func(ctx context.Context, onEvent func(Event)) ([]discovery.DiscoveredDevice, error)
 
-func printDiscoveredDevice(count int, device discovery.DiscoveredDevice) {
-	fmt.Printf("  %d. %s (%s) at %s\n", count, device.Instance, device.Type, device.Host)
+// WithDiscovery replaces the mDNS discovery of the interactive flow, e.g. with a subnet scan
+// or Discover with discovery options
+func WithDiscovery(discover DiscoverFunc) Option {
+	return func(o *options) {
+		o.discover = discover
+	}
 }
 
-func confirmDevicesFound() bool {
-	fmt.Println()
-	fmt.Print("Is this everything? [Y/n]: ")
+// EventType identifies a step of the pairing flow
+type EventType string
+
+const (
+	EventDiscovered       EventType = "discovered"         // Device found, or its status improved
+	EventWaitingForButton EventType = "waiting-for-button" // Token requested, waiting for the button press
+	EventPaired           EventType = "paired"             // Token received and verified
+	EventFailed           EventType = "failed"             // Pairing failed or timed out
+)
 
-	var response string
-	fmt.Scanln(&response)
-	response = strings.ToLower(strings.TrimSpace(response))
+// ErrSaveToken is returned by Pair for paired devices whose token could not be saved to the token store
+var ErrSaveToken = errors.New("saving token")
 
-	if response == "n" || response == "no" {
-		fmt.Println()
-		fmt.Println("Discovery aborted.")
-		fmt.Println("Please ensure all devices are powered on and on the same network, then try again.")
-		return false
-	}
+// Event is passed to the event callback during discovery and pairing
+type Event struct {
+	Type    EventType
+	Device  discovery.DiscoveredDevice
+	Attempt int           // Token request attempt (1-MaxAttempts), EventWaitingForButton only
+	Paired  *PairedDevice // EventPaired only
+	Err     error         // EventFailed only
+}
 
-	return true
+// ValidateName checks a user name against the HomeWizard API requirements
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid name: must be 1-40 characters (a-z, A-Z, 0-9, -, _, \\, /, #, spaces)")
+	}
+	return nil
 }
 
-func discoverInteractively(timeoutSec int) []discovery.DiscoveredDevice {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+// Discover searches for HomeWizard devices until no new device was found for DefaultQuietPeriod
+// or the context is done. EventDiscovered is emitted for each device, and again when its status improves.
+// onEvent may be nil.
+func Discover(ctx context.Context, onEvent func(Event), opts ...discovery.Option) ([]discovery.DiscoveredDevice, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	deviceChan := make(chan discovery.DiscoveredDevice, 10)
-	done := make(chan struct{})
+	deviceC := make(chan discovery.DiscoveredDevice)
+	errC := make(chan error, 1)
 
-	// Start discovery
 	go func() {
-		discovery.DiscoverDevices(ctx, func(device discovery.DiscoveredDevice) {
-			deviceChan <- device
-		})
-		close(done)
+		errC <- discovery.DiscoverDevices(ctx, func(d discovery.DiscoveredDevice) {
+			select {
+			case deviceC <- d:
+			case <-ctx.Done():
+			}
+		}, opts...)
 	}()
 
-	devices := make([]discovery.DiscoveredDevice, 0)
-	spinner := newSpinner()
-	spinnerTicker := time.NewTicker(100 * time.Millisecond)
-	defer spinnerTicker.Stop()
-
-	// Stop searching after this period of no new devices
-	quietPeriod := 3 * time.Second
-	var quietTimer <-chan time.Time
-
-	fmt.Printf("\r%s Searching...", spinner.frames[0])
+	var (
+		devices    []discovery.DiscoveredDevice
+		quietTimer <-chan time.Time
+	)
 
 	for {
 		select {
-		case device := <-deviceChan:
-			// Clear spinner and print device
-			spinner.clear()
-			devices = append(devices, device)
-			printDiscoveredDevice(len(devices), device)
+		case d := <-deviceC:
+			// A device is reported again when its status improves, e.g. v2 announcement after v1
+			if idx := slices.IndexFunc(devices, func(e discovery.DiscoveredDevice) bool {
+				return e.Serial != "" && e.Serial == d.Serial
+			}); idx >= 0 {
+				devices[idx] = d
+			} else {
+				devices = append(devices, d)
+			}
 
-			// Start/reset quiet period timer
-			quietTimer = time.After(quietPeriod)
+			emit(onEvent, Event{Type: EventDiscovered, Device: d})
 
-		case <-spinnerTicker.C:
-			spinner.tick()
+			// Start/reset quiet period timer
+			quietTimer = time.After(DefaultQuietPeriod)
 
 		case <-quietTimer:
 			// No new devices found recently, stop searching
 			cancel()
-			<-done // Wait for discovery goroutine to finish
-			spinner.stop()
-
-			// Ask user if satisfied with results
-			if len(devices) > 0 && !confirmDevicesFound() {
-				return nil
-			}
-			return devices
+			<-errC
+			return devices, nil
 
-		case <-done:
-			// Overall timeout reached
-			spinner.stop()
-
-			// Ask user if satisfied with results
-			if len(devices) > 0 && !confirmDevicesFound() {
-				return nil
+		case err := <-errC:
+			// Overall timeout reached or discovery failed
+			if err != nil && ctx.Err() == nil {
+				return devices, err
 			}
-			return devices
+			return devices, nil
 		}
 	}
 }
 
-type deviceStatus struct {
-	device  discovery.DiscoveredDevice
-	status  string
-	attempt int
-	token   string
-	err     error
-}
+// Pair pairs the devices in parallel, the user has to press the button on each device
+// Each new token is verified on the device WebSocket, pairing fails if it is rejected.
+// Devices without API v2 fail immediately. Events are serialized, onEvent may be nil.
+// Returns the paired devices, together with an error for each device that failed or whose token could not be saved.
+func Pair(ctx context.Context, devices []discovery.DiscoveredDevice, name string, onEvent func(Event), opts ...Option) ([]PairedDevice, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
 
-func pairDevicesParallel(devices []discovery.DiscoveredDevice, name string) []PairedDevice {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-	defer cancel()
+	o := applyOptions(opts...)
 
-	statuses := make([]*deviceStatus, len(devices))
-	var statusMu sync.Mutex
-	var wg sync.WaitGroup
+	ctx, cancel := context.WithTimeout(ctx, PairTimeout)
+	defer cancel()
 
-	// Initialize and print status for each device
-	for i := range devices {
-		statuses[i] = &deviceStatus{
-			device: devices[i],
-			status: "initializing...",
-		}
-		fmt.Printf("[%d] %s: %s\n", i+1, statuses[i].device.Host, statuses[i].status)
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		res  = make([]*PairedDevice, len(devices))
+		errs = make([]error, len(devices))
+	)
+
+	emitLocked := func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(onEvent, e)
 	}
 
-	totalLines := len(statuses)
-
-	// Start pairing goroutine for each device
-	for i := range devices {
+	for i, d := range devices {
 		wg.Add(1)
-		go func(idx int) {
+		go func() {
 			defer wg.Done()
 
-			status := statuses[idx]
-			device := devices[idx]
-
-			token, err := pairDeviceWithContext(ctx, device.Host, name, func(attempt int) {
-				statusMu.Lock()
-				defer statusMu.Unlock()
-				status.attempt = attempt
-				status.status = fmt.Sprintf("waiting for button press (attempt %d/36)...", attempt)
-				updateStatusLine(idx, status, totalLines)
-			})
+			paired, err := pairDevice(ctx, d, name, emitLocked)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", d.Host, err)
+				emitLocked(Event{Type: EventFailed, Device: d, Err: err})
+				return
+			}
 
-			statusMu.Lock()
-			defer statusMu.Unlock()
+			res[i] = &paired
+			emitLocked(Event{Type: EventPaired, Device: d, Paired: &paired})
 
-			if err != nil {
-				status.err = err
-				status.status = fmt.Sprintf("✗ FAILED: %v", err)
-			} else {
-				status.token = token
-				status.status = "✓ SUCCESS"
+			// The token is still returned if it could not be saved
+			if o.store != nil {
+				if err := o.store.SaveToken(paired.Serial, paired.Token); err != nil {
+					errs[i] = fmt.Errorf("%s: %w: %w", d.Host, ErrSaveToken, err)
+				}
 			}
-			updateStatusLine(idx, status, totalLines)
-		}(i)
+		}()
 	}
 
 	wg.Wait()
-	fmt.Println()
 
-	// Build result - pre-allocate with capacity
 	paired := make([]PairedDevice, 0, len(devices))
-	failedCount := 0
-
-	for _, status := range statuses {
-		if status.token != "" {
-			paired = append(paired, PairedDevice{
-				Host:  status.device.Host,
-				Token: status.token,
-				Type:  status.device.Type,
-			})
-		} else {
-			failedCount++
+	for _, p := range res {
+		if p != nil {
+			paired = append(paired, *p)
 		}
 	}
 
-	if failedCount > 0 {
-		fmt.Printf("\nWarning: %d device(s) failed to pair\n", failedCount)
+	return paired, errors.Join(errs...)
+}
+
+// PairHost pairs a single device by host without discovery
+func PairHost(ctx context.Context, host, name string, onEvent func(Event), opts ...Option) (PairedDevice, error) {
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.TrimPrefix(host, "https://")
+
+	paired, err := Pair(ctx, []discovery.DiscoveredDevice{{Host: host}}, name, onEvent, opts...)
+	if len(paired) == 0 {
+		return PairedDevice{}, err
 	}
 
-	return paired
+	return paired[0], err
 }
 
-func updateStatusLine(line int, status *deviceStatus, totalLines int) {
-	// Move cursor up to the line, clear it, and print new status
-	fmt.Printf("\033[%dA\r\033[K[%d] %s: %s\033[%dB\r",
-		totalLines-line, line+1, status.device.Host, status.status, totalLines-line)
+// emit passes an event to the callback, if any
+func emit(onEvent func(Event), e Event) {
+	if onEvent != nil {
+		onEvent(e)
+	}
 }
 
-func pairDeviceWithContext(ctx context.Context, host, name string, onAttempt func(int)) (string, error) {
-	uri := fmt.Sprintf("https://%s", host)
+// pairDevice requests a token until the button is pressed or the context is done
+func pairDevice(ctx context.Context, d discovery.DiscoveredDevice, name string, onEvent func(Event)) (PairedDevice, error) {
+	switch d.Status {
+	case discovery.StatusV1Only:
+		return PairedDevice{}, errors.New("device only offers the legacy v1 API")
+	case discovery.StatusAPIDisabled:
+		return PairedDevice{}, errors.New("local API is disabled")
+	}
 
-	// Create HTTP client with insecure transport for self-signed certs
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-		Timeout: 3 * time.Second,
+	token, err := pairDeviceWithContext(ctx, d.Host, name, func(attempt int) {
+		onEvent(Event{Type: EventWaitingForButton, Device: d, Attempt: attempt})
+	})
+	if err != nil {
+		return PairedDevice{}, err
+	}
+
+	return verifyToken(ctx, d, token)
+}
+
+// verify checks a token on the device WebSocket, replaced in tests
+var verify = device.Verify
+
+// verifyToken checks the token on the device WebSocket and completes the paired device from its device info
+// Verification gets its own timeout, a button press just before the pairing deadline must still be verified.
+// Cancelling the context still aborts verification.
+func verifyToken(ctx context.Context, d discovery.DiscoveredDevice, token string) (PairedDevice, error) {
+	vctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), verifyTimeout)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			cancel()
+		}
+	})
+	defer stop()
+
+	info, err := verify(vctx, d.Host, token)
+	if err != nil {
+		return PairedDevice{}, fmt.Errorf("verifying token: %w", err)
+	}
+
+	if d.Serial != "" && info.Serial != d.Serial {
+		return PairedDevice{}, fmt.Errorf("verifying token: serial %s does not match discovered %s", info.Serial, d.Serial)
+	}
+
+	res := PairedDevice{
+		Host:            d.Host,
+		Token:           token,
+		Type:            d.Type,
+		ProductType:     info.ProductType,
+		ProductName:     info.ProductName,
+		Serial:          info.Serial,
+		FirmwareVersion: info.FirmwareVersion,
+	}
+
+	// Devices paired without discovery only learn their type now
+	if p, ok := device.LookupProduct(info.ProductType); ok {
+		res.Type = p.DeviceType
 	}
 
-	ticker := time.NewTicker(5 * time.Second)
+	return res, nil
+}
+
+func pairDeviceWithContext(ctx context.Context, host, name string, onAttempt func(int)) (string, error) {
+	uri := fmt.Sprintf("https://%s", host)
+	client := newPairingClient()
+
+	ticker := time.NewTicker(attemptInterval)
 	defer ticker.Stop()
 
 	attempt := 0
@@ -344,7 +360,7 @@ func pairDeviceWithContext(ctx context.Context, host, name string, onAttempt fun
 			attempt++
 			onAttempt(attempt)
 
-			token, err := requestToken(client, uri, name)
+			token, err := requestToken(ctx, client, uri, name, "")
 			if err == nil {
 				return token, nil
 			}
@@ -354,12 +370,22 @@ func pairDeviceWithContext(ctx context.Context, host, name string, onAttempt fun
 			}
 
 		case <-ctx.Done():
-			return "", fmt.Errorf("timeout after 3 minutes")
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf("timeout after %d attempts", attempt)
+			}
+			return "", ctx.Err()
 		}
 	}
 }
 
-func requestToken(client *http.Client, uri, name string) (string, error) {
+// newPairingClient creates an HTTP client with a short timeout, as token requests are repeated until the button is pressed
+func newPairingClient() *http.Client {
+	return device.NewHTTPClient(3 * time.Second)
+}
+
+// requestToken creates a user and returns its token
+// Without token the button must have been pressed, with the token of an existing user no button press is needed.
+func requestToken(ctx context.Context, client *http.Client, uri, name, token string) (string, error) {
 	endpoint := fmt.Sprintf("%s/api/user", uri)
 
 	reqBody := struct {
@@ -368,19 +394,11 @@ func requestToken(client *http.Client, uri, name string) (string, error) {
 		Name: fmt.Sprintf("local/%s", name),
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	req, err := device.NewAPIRequest(ctx, http.MethodPost, endpoint, token, request.MarshalJSON(reqBody))
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(jsonData)))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-Version", "2")
-
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -418,84 +436,3 @@ func isButtonPressRequired(err error) bool {
 	}
 	return false
 }
-
-func printHomeWizardMultiConfig(devices []PairedDevice) {
-	fmt.Println()
-	fmt.Println("========================================")
-	fmt.Println("Configuration Complete!")
-	fmt.Println("========================================")
-	fmt.Println()
-	fmt.Println("Add this to your evcc.yaml configuration:")
-	fmt.Println()
-
-	// Categorize devices
-	var p1Meter *PairedDevice
-	kwhMeters := make([]PairedDevice, 0)
-	batteries := make([]PairedDevice, 0)
-
-	for i := range devices {
-		switch devices[i].Type {
-		case device.DeviceTypeP1Meter:
-			if p1Meter == nil {
-				p1Meter = &devices[i]
-			}
-		case device.DeviceTypeKWHMeter:
-			kwhMeters = append(kwhMeters, devices[i])
-		case device.DeviceTypeBattery:
-			batteries = append(batteries, devices[i])
-		default:
-			// Unknown type - assume first is P1 meter if not set
-			if p1Meter == nil {
-				p1Meter = &devices[i]
-			}
-		}
-	}
-
-	fmt.Println("meters:")
-
-	// Print P1 meter (grid) configuration
-	if p1Meter != nil {
-		fmt.Println("- name: grid")
-		fmt.Println("  type: homewizard-v2")
-		fmt.Println("  usage: grid")
-		fmt.Printf("  host: %s\n", p1Meter.Host)
-		fmt.Printf("  token: %s\n", p1Meter.Token)
-		fmt.Println()
-	}
-
-	// Print kWh meter (pv) configurations
-	for i, kwh := range kwhMeters {
-		meterName := "pv"
-		if i > 0 {
-			meterName = fmt.Sprintf("pv%d", i+1)
-		}
-		fmt.Printf("- name: %s\n", meterName)
-		fmt.Println("  type: homewizard-v2")
-		fmt.Println("  usage: pv    # or \"charge\", if you use it for something else")
-		fmt.Printf("  host: %s\n", kwh.Host)
-		fmt.Printf("  token: %s\n", kwh.Token)
-		fmt.Println()
-	}
-
-	// Print battery configurations
-	for i, bat := range batteries {
-		meterName := "battery"
-		if i > 0 {
-			meterName = fmt.Sprintf("battery%d", i+1)
-		}
-		fmt.Printf("- name: %s\n", meterName)
-		fmt.Println("  type: homewizard-v2")
-		fmt.Println("  usage: battery")
-		fmt.Printf("  host: %s\n", bat.Host)
-		fmt.Printf("  token: %s\n", bat.Token)
-
-		fmt.Println()
-	}
-
-	// Print helpful notes
-	if len(devices) > 0 {
-		fmt.Println("# Notes:")
-		fmt.Println("# - Make sure to add these devices to your \"sites\" as well")
-		fmt.Println()
-	}
-}
@@ -0,0 +1,55 @@
+package pairing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+)
+
+// stubVerify replaces the token verification for the duration of the test
+func stubVerify(t *testing.T, fn func(ctx context.Context, host, token string) (device.DeviceInfo, error)) {
+	t.Helper()
+	prev := verify
+	verify = fn
+	t.Cleanup(func() { verify = prev })
+}
+
+func TestVerifyTokenNearDeadline(t *testing.T) {
+	// The button was pressed just before the pairing deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	stubVerify(t, func(vctx context.Context, host, token string) (device.DeviceInfo, error) {
+		<-ctx.Done()
+		if err := vctx.Err(); err != nil {
+			return device.DeviceInfo{}, err
+		}
+		return device.DeviceInfo{ProductType: "HWE-P1", Serial: "abc"}, nil
+	})
+
+	res, err := verifyToken(ctx, discovery.DiscoveredDevice{Host: "192.168.1.2", Serial: "abc"}, "token")
+	if err != nil {
+		t.Fatalf("verifyToken: %v", err)
+	}
+	if res.Token != "token" || res.Type != device.DeviceTypeP1Meter {
+		t.Errorf("paired = %+v", res)
+	}
+}
+
+func TestVerifyTokenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stubVerify(t, func(vctx context.Context, host, token string) (device.DeviceInfo, error) {
+		cancel()
+		<-vctx.Done()
+		return device.DeviceInfo{}, vctx.Err()
+	})
+
+	if _, err := verifyToken(ctx, discovery.DiscoveredDevice{Host: "192.168.1.2"}, "token"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
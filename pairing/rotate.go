@@ -0,0 +1,145 @@
+package pairing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mluiten/evcc-homewizard-v2/device"
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+)
+
+// Rotation is the result of a token rotation
+type Rotation struct {
+	Device        PairedDevice // Device with the new token
+	OldUser       string       // Deleted user of the old token
+	NewUser       string       // User of the new token
+	UpdatedMeters []string     // Meters in the config file updated with the new token
+}
+
+// RotateToken replaces the token of a device with a token of a new user and deletes the old user
+//
+// The new user is created with the old token, falling back to waiting for the button press if the device
+// requires it (EventWaitingForButton). The new token is verified and saved to the token store and
+// config file, if configured, before the old user is deleted. If saving fails the new user is deleted
+// again and the old token remains valid. Failures are reported as EventFailed, like when pairing.
+func RotateToken(ctx context.Context, host, token, name string, onEvent func(Event), opts ...Option) (res Rotation, err error) {
+	d := discovery.DiscoveredDevice{Host: host}
+
+	defer func() {
+		if err != nil {
+			emit(onEvent, Event{Type: EventFailed, Device: d, Err: err})
+		}
+	}()
+
+	if err := ValidateName(name); err != nil {
+		return Rotation{}, err
+	}
+
+	o := applyOptions(opts...)
+
+	info, oldUser, err := currentUser(ctx, host, token)
+	if err != nil {
+		return Rotation{}, err
+	}
+
+	res = Rotation{
+		OldUser: oldUser,
+		NewUser: "local/" + name,
+	}
+
+	if res.NewUser == res.OldUser {
+		return Rotation{}, fmt.Errorf("new user name must differ from current user %s", oldUser)
+	}
+
+	d = discovery.DiscoveredDevice{Host: host, Serial: info.Serial, ProductType: info.ProductType, ProductName: info.ProductName}
+	if p, ok := device.LookupProduct(info.ProductType); ok {
+		d.Type = p.DeviceType
+	}
+
+	newToken, err := requestToken(ctx, newPairingClient(), fmt.Sprintf("https://%s", host), name, token)
+	if isButtonPressRequired(err) {
+		ctx, cancel := context.WithTimeout(ctx, PairTimeout)
+		defer cancel()
+
+		newToken, err = pairDeviceWithContext(ctx, host, name, func(attempt int) {
+			emit(onEvent, Event{Type: EventWaitingForButton, Device: d, Attempt: attempt})
+		})
+	}
+	if err != nil {
+		return Rotation{}, fmt.Errorf("creating user: %w", err)
+	}
+
+	paired, err := verifyToken(ctx, d, newToken)
+	if err == nil {
+		res.Device = paired
+		res.UpdatedMeters, err = saveRotatedToken(o, info.Serial, token, newToken)
+	}
+
+	if err != nil {
+		// Don't leave an unused user behind, the old token remains valid
+		if derr := device.DeleteUser(ctx, host, token, res.NewUser); derr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting new user: %w", derr))
+		}
+		return Rotation{}, err
+	}
+
+	if err := device.DeleteUser(ctx, host, newToken, oldUser); err != nil {
+		return res, fmt.Errorf("deleting old user: %w", err)
+	}
+
+	emit(onEvent, Event{Type: EventPaired, Device: d, Paired: &res.Device})
+
+	return res, nil
+}
+
+// currentUser verifies the token and returns the device information and the name of the token's user
+func currentUser(ctx context.Context, host, token string) (device.DeviceInfo, string, error) {
+	vctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	info, err := device.Verify(vctx, host, token)
+	if err != nil {
+		return device.DeviceInfo{}, "", fmt.Errorf("verifying token: %w", err)
+	}
+
+	users, err := device.ListUsers(ctx, host, token)
+	if err != nil {
+		return device.DeviceInfo{}, "", fmt.Errorf("listing users: %w", err)
+	}
+
+	for _, u := range users {
+		if u.Current {
+			return info, u.Name, nil
+		}
+	}
+
+	return device.DeviceInfo{}, "", errors.New("listing users: user of the token not found")
+}
+
+// saveRotatedToken saves the new token to the token store and replaces it in the config file, if configured
+// The token store is restored if the config file cannot be updated.
+func saveRotatedToken(o options, serial, oldToken, newToken string) ([]string, error) {
+	if o.store != nil {
+		if err := o.store.SaveToken(serial, newToken); err != nil {
+			return nil, fmt.Errorf("saving token: %w", err)
+		}
+	}
+
+	if o.configFile == "" {
+		return nil, nil
+	}
+
+	meters, err := ReplaceConfigTokenFile(o.configFile, oldToken, newToken)
+	if err != nil {
+		err = fmt.Errorf("updating config: %w", err)
+
+		if o.store != nil {
+			if serr := o.store.SaveToken(serial, oldToken); serr != nil {
+				err = errors.Join(err, fmt.Errorf("restoring token: %w", serr))
+			}
+		}
+	}
+
+	return meters, err
+}
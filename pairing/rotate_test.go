@@ -0,0 +1,57 @@
+package pairing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRotateTokenFailed(t *testing.T) {
+	var events []Event
+	_, err := RotateToken(context.Background(), "192.0.2.1", "token", "invalid name!", func(e Event) {
+		events = append(events, e)
+	})
+	if err == nil {
+		t.Fatal("invalid name accepted")
+	}
+
+	if len(events) != 1 || events[0].Type != EventFailed || events[0].Device.Host != "192.0.2.1" || events[0].Err == nil {
+		t.Errorf("events = %+v, want a single failed event", events)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		switch {
+		case r.Method != http.MethodPost || r.URL.Path != "/api/user" || body.Name != "local/evcc":
+			t.Errorf("request %s %s %+v", r.Method, r.URL.Path, body)
+		case r.Header.Get("X-Api-Version") != "2":
+			t.Errorf("api version header = %q", r.Header.Get("X-Api-Version"))
+		case r.Header.Get("Authorization") != "Bearer old":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"user:creation-not-enabled"}`))
+			return
+		}
+
+		w.Write([]byte(`{"token":"new","name":"local/evcc"}`))
+	}))
+	defer srv.Close()
+
+	token, err := requestToken(context.Background(), newPairingClient(), srv.URL, "evcc", "old")
+	if err != nil || token != "new" {
+		t.Errorf("token = %q, %v, want new", token, err)
+	}
+
+	if _, err := requestToken(context.Background(), newPairingClient(), srv.URL, "evcc", ""); !isButtonPressRequired(err) {
+		t.Errorf("unauthenticated request: %v, want button press required", err)
+	}
+}
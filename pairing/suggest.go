@@ -0,0 +1,142 @@
+package pairing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// Role suggestion parameters
+const (
+	// DefaultObserveDuration is how long a kWh meter is observed before suggesting its role
+	DefaultObserveDuration = 10 * time.Second
+
+	observeInterval  = time.Second
+	minFlowEnergykWh = 1.0    // Counters below this have not seen meaningful energy flow
+	standbyPowerW    = 50.0   // Power below this is considered idle
+	chargeMinPowerW  = 1380.0 // 6 A at 230 V, the minimum EV charging power
+	chargeSteadiness = 0.8    // Minimum power relative to the average while charging
+	chargeEnergykWh  = 50.0   // Imported energy of a wallbox that has charged a few sessions
+	chargeExportMax  = 0.01   // Maximum export share of a charger, which never feeds in
+	maxConfidence    = 0.95
+)
+
+// RoleSuggestion is a suggested evcc usage for a kWh meter based on its observed power flow
+type RoleSuggestion struct {
+	Usage      adapter.Usage
+	Confidence float64 // 0-1
+	Reason     string
+}
+
+// SuggestRole observes a paired kWh meter and suggests its usage from the direction and magnitude of the power flow
+// pv for net export, charge for a large steady single direction import or an idle meter that only ever imported a lot, aux otherwise
+func SuggestRole(ctx context.Context, d PairedDevice, duration time.Duration) (RoleSuggestion, error) {
+	if d.Type != device.DeviceTypeKWHMeter {
+		return RoleSuggestion{}, fmt.Errorf("role suggestion not supported for %s", d.Type)
+	}
+
+	meter := device.NewKWHMeterDevice(d.Host, d.Token, device.DefaultTimeout)
+	if err := meter.StartAndWait(device.DefaultTimeout); err != nil {
+		return RoleSuggestion{}, err
+	}
+	defer meter.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	ticker := time.NewTicker(observeInterval)
+	defer ticker.Stop()
+
+	var samples []device.KWHMeasurement
+
+	for {
+		select {
+		case <-ticker.C:
+			if m, err := meter.GetMeasurement(); err == nil {
+				samples = append(samples, m)
+			}
+
+		case <-ctx.Done():
+			if len(samples) == 0 {
+				return RoleSuggestion{}, errors.New("no measurements received")
+			}
+			return suggestRole(samples), nil
+		}
+	}
+}
+
+// suggestRole classifies a kWh meter from its measurements
+// HomeWizard reports positive power for import (consumption) and negative power for export (production)
+func suggestRole(samples []device.KWHMeasurement) RoleSuggestion {
+	last := samples[len(samples)-1]
+	imp, exp := last.EnergyImportkWh, last.EnergyExportkWh
+
+	var sum float64
+	minPower := math.Inf(1)
+	for _, m := range samples {
+		sum += m.PowerW
+		minPower = min(minPower, m.PowerW)
+	}
+	avgPower := sum / float64(len(samples))
+
+	var exportShare float64
+	if imp+exp > 0 {
+		exportShare = exp / (imp + exp)
+	}
+
+	switch {
+	case imp+exp < minFlowEnergykWh && math.Abs(avgPower) < standbyPowerW:
+		return RoleSuggestion{
+			Usage:      adapter.UsageAux,
+			Confidence: 0.2,
+			Reason:     "no energy flow observed yet",
+		}
+
+	case avgPower <= -standbyPowerW:
+		// Producing right now
+		return RoleSuggestion{
+			Usage:      adapter.UsagePV,
+			Confidence: min(0.6+exportShare/2, maxConfidence),
+			Reason:     fmt.Sprintf("exporting %.0f W, %.0f kWh exported in total", -avgPower, exp),
+		}
+
+	case exportShare >= 0.5:
+		return RoleSuggestion{
+			Usage:      adapter.UsagePV,
+			Confidence: min(exportShare, maxConfidence),
+			Reason:     fmt.Sprintf("net export, %.0f kWh exported and %.0f kWh imported", exp, imp),
+		}
+
+	case avgPower >= chargeMinPowerW && minPower >= chargeSteadiness*avgPower:
+		// Steady large import, typical for an EV charging
+		confidence := 0.7
+		if exportShare < chargeExportMax {
+			confidence = 0.85
+		}
+		return RoleSuggestion{
+			Usage:      adapter.UsageCharge,
+			Confidence: confidence,
+			Reason:     fmt.Sprintf("steady import of %.0f W", avgPower),
+		}
+
+	case math.Abs(avgPower) < standbyPowerW && imp >= chargeEnergykWh && exportShare < chargeExportMax:
+		// Idle between charging sessions, the counters show a large import only
+		return RoleSuggestion{
+			Usage:      adapter.UsageCharge,
+			Confidence: 0.6,
+			Reason:     fmt.Sprintf("idle, %.0f kWh imported without export", imp),
+		}
+
+	default:
+		return RoleSuggestion{
+			Usage:      adapter.UsageAux,
+			Confidence: 0.5,
+			Reason:     fmt.Sprintf("import of %.0f W without charging pattern, %.0f kWh imported in total", max(avgPower, 0), imp),
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package pairing
+
+import (
+	"testing"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+)
+
+// kwhSamples returns measurements with the given power readings and energy counters
+func kwhSamples(importkWh, exportkWh float64, power ...float64) []device.KWHMeasurement {
+	res := make([]device.KWHMeasurement, 0, len(power))
+	for _, p := range power {
+		var m device.KWHMeasurement
+		m.PowerW = p
+		m.EnergyImportkWh = importkWh
+		m.EnergyExportkWh = exportkWh
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestSuggestRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []device.KWHMeasurement
+		usage   adapter.Usage
+	}{
+		{"new meter", kwhSamples(0.2, 0, 0, 5), adapter.UsageAux},
+		{"producing", kwhSamples(1, 500, -1500, -1600), adapter.UsagePV},
+		{"idle inverter", kwhSamples(10, 500, 0, 0), adapter.UsagePV},
+		{"charging", kwhSamples(300, 0, 7400, 7300, 7350), adapter.UsageCharge},
+		{"idle wallbox", kwhSamples(300, 0, 0, 1), adapter.UsageCharge},
+		{"idle with export", kwhSamples(300, 10, 0, 1), adapter.UsageAux},
+		{"idle small import", kwhSamples(20, 0, 0, 1), adapter.UsageAux},
+		{"fluctuating load", kwhSamples(300, 0, 2000, 200, 1800), adapter.UsageAux},
+		{"small load", kwhSamples(300, 0, 400, 420), adapter.UsageAux},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := suggestRole(tc.samples)
+			if res.Usage != tc.usage {
+				t.Errorf("usage = %s (%s), want %s", res.Usage, res.Reason, tc.usage)
+			}
+			if res.Confidence <= 0 || res.Confidence > maxConfidence {
+				t.Errorf("confidence = %v out of range", res.Confidence)
+			}
+		})
+	}
+}
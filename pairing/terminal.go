@@ -0,0 +1,645 @@
+package pairing
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mluiten/evcc-homewizard-v2/adapter"
+	"github.com/mluiten/evcc-homewizard-v2/device"
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+)
+
+// stdin reads the answers of interactive prompts, names may contain spaces
+var stdin = bufio.NewReader(os.Stdin)
+
+// meterNamePattern are the characters allowed in an evcc meter name
+var meterNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// DiscoverAndPairDevices executes the interactive pairing flow
+// Cancelling the context stops discovery, pairing and role observation.
+func DiscoverAndPairDevices(ctx context.Context, name string, timeout int, opts ...Option) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	// Discovery mode
+	fmt.Println("HomeWizard Device Discovery")
+	fmt.Println("===========================")
+	fmt.Println()
+	fmt.Printf("Scanning network (max %ds)...\n", timeout)
+	fmt.Println()
+
+	o := applyOptions(opts...)
+
+	devices, err := discoverInteractively(ctx, timeout, o.discover)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+
+	if len(devices) == 0 {
+		return fmt.Errorf("no HomeWizard devices found on network 😞")
+	}
+
+	// Ask user if satisfied with results
+	if !confirmDevicesFound() {
+		return nil
+	}
+
+	// Devices without API v2 cannot be paired, explain how to fix them
+	devices = pairableDevices(devices)
+
+	if len(devices) == 0 {
+		return fmt.Errorf("no HomeWizard devices with API v2 found on network")
+	}
+
+	// Let the user pick the devices to pair
+	devices = selectDevices(devices)
+
+	if len(devices) == 0 {
+		fmt.Println()
+		fmt.Println("No devices selected.")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("HomeWizard Device Pairing")
+	fmt.Println("=========================")
+	fmt.Println()
+	fmt.Println("Press the button on your devices:")
+	fmt.Println()
+
+	// Pair all devices in parallel
+	paired := pairDevicesParallel(ctx, devices, name, opts...)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Let the user assign role and name, kWh meter roles are suggested from their power flow
+	suggestions := suggestRoles(ctx, paired)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	meters := assignMeters(paired, suggestions)
+
+	// Print configuration
+	return printHomeWizardMultiConfig(meters, o)
+}
+
+// PairSingleDevice pairs a specific device without discovery
+// Cancelling the context stops waiting for the button press.
+func PairSingleDevice(ctx context.Context, host, name string, opts ...Option) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.TrimPrefix(host, "https://")
+
+	fmt.Println("HomeWizard Device Pairing")
+	fmt.Println("=========================")
+	fmt.Println()
+	fmt.Printf("Device: %s\n", host)
+	fmt.Println()
+	fmt.Println("Press the button on your device:")
+	fmt.Println()
+
+	lines := newStatusLines([]discovery.DiscoveredDevice{{Host: host}})
+
+	paired, err := PairHost(ctx, host, name, lines.update, opts...)
+	if paired.Token == "" {
+		fmt.Println()
+		return fmt.Errorf("pairing failed: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Println("Pairing Successful!")
+	fmt.Println("========================================")
+	fmt.Println()
+	fmt.Printf("Product:  %s (%s)\n", paired.ProductName, paired.ProductType)
+	fmt.Printf("Serial:   %s\n", paired.Serial)
+	fmt.Printf("Firmware: %s\n", paired.FirmwareVersion)
+	fmt.Printf("Token:    %s\n", paired.Token)
+	fmt.Println()
+
+	return nil
+}
+
+type discoverySpinner struct {
+	mu     sync.Mutex
+	frames []string
+	idx    int
+	active bool
+}
+
+func newSpinner() *discoverySpinner {
+	return &discoverySpinner{
+		frames: []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
+		active: true,
+	}
+}
+
+// run animates the spinner until the context is done
+func (s *discoverySpinner) run(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	fmt.Printf("\r%s Searching...", s.frames[0])
+
+	for {
+		select {
+		case <-ticker.C:
+			s.tick()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *discoverySpinner) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active {
+		s.idx = (s.idx + 1) % len(s.frames)
+		fmt.Printf("\r%s Searching...", s.frames[s.idx])
+	}
+}
+
+// print clears the spinner and prints a line in its place
+func (s *discoverySpinner) print(print func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clear()
+	print()
+}
+
+func (s *discoverySpinner) clear() {
+	fmt.Print("\r\033[K")
+}
+
+func (s *discoverySpinner) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clear()
+	s.active = false
+}
+
+func printDiscoveredDevice(count int, device discovery.DiscoveredDevice) {
+	fmt.Printf("  %d. %s (%s) at %s", count, device.Instance, device.Type, device.Host)
+	if len(device.Interfaces) > 0 {
+		fmt.Printf(" via %s", strings.Join(device.Interfaces, ", "))
+	}
+	switch device.Status {
+	case discovery.StatusV1Only:
+		fmt.Print(" [v1 only]")
+	case discovery.StatusAPIDisabled:
+		fmt.Print(" [API disabled]")
+	}
+	fmt.Println()
+}
+
+// pairableDevices returns the devices with API v2 and prints guidance for the others
+func pairableDevices(devices []discovery.DiscoveredDevice) []discovery.DiscoveredDevice {
+	pairable := make([]discovery.DiscoveredDevice, 0, len(devices))
+	var skipped int
+
+	for _, device := range devices {
+		if device.Status == discovery.StatusReady {
+			pairable = append(pairable, device)
+			continue
+		}
+
+		if skipped == 0 {
+			fmt.Println()
+			fmt.Println("The following devices cannot be paired yet:")
+		}
+		skipped++
+
+		fmt.Println()
+		fmt.Printf("  %s (%s) at %s\n", device.Instance, device.ProductType, device.Host)
+
+		switch device.Status {
+		case discovery.StatusV1Only:
+			fmt.Println("    This device only offers the legacy v1 API.")
+			fmt.Println("    Update its firmware in the HomeWizard Energy app, API v2 is included in recent firmware.")
+		case discovery.StatusAPIDisabled:
+			fmt.Println("    The local API of this device is switched off.")
+			fmt.Println("    Enable it in the HomeWizard Energy app: Settings > Meters > your device > Local API.")
+		}
+	}
+
+	if skipped > 0 {
+		fmt.Println()
+		fmt.Println("Run pairing again after fixing these devices to include them.")
+	}
+
+	return pairable
+}
+
+// prompt asks a question and returns the trimmed answer, or the default if empty
+func prompt(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	response, _ := stdin.ReadString('\n')
+	if response = strings.TrimSpace(response); response == "" {
+		return def
+	}
+	return response
+}
+
+func confirmDevicesFound() bool {
+	fmt.Println()
+	fmt.Print("Is this everything? [Y/n]: ")
+
+	response, _ := stdin.ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+
+	if response == "n" || response == "no" {
+		fmt.Println()
+		fmt.Println("Discovery aborted.")
+		fmt.Println("Please ensure all devices are powered on and on the same network, then try again.")
+		return false
+	}
+
+	return true
+}
+
+func discoverInteractively(ctx context.Context, timeoutSec int, discover DiscoverFunc) ([]discovery.DiscoveredDevice, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	spinner := newSpinner()
+	spinnerCtx, stopSpinner := context.WithCancel(ctx)
+	go spinner.run(spinnerCtx)
+
+	// Serials in order of discovery, used for numbering
+	var serials []string
+
+	devices, err := discover(ctx, func(e Event) {
+		spinner.print(func() {
+			// A device is reported again when its status improves, e.g. v2 announcement after v1
+			if idx := slices.IndexFunc(serials, func(s string) bool {
+				return s != "" && s == e.Device.Serial
+			}); idx >= 0 {
+				printDiscoveredDevice(idx+1, e.Device)
+				return
+			}
+
+			serials = append(serials, e.Device.Serial)
+			printDiscoveredDevice(len(serials), e.Device)
+		})
+	})
+
+	stopSpinner()
+	spinner.stop()
+
+	return devices, err
+}
+
+// selectDevices asks which of the devices to pair, all by default
+func selectDevices(devices []discovery.DiscoveredDevice) []discovery.DiscoveredDevice {
+	if len(devices) == 1 {
+		return devices
+	}
+
+	fmt.Println()
+	fmt.Println("Devices to pair:")
+	for i, d := range devices {
+		printDiscoveredDevice(i+1, d)
+	}
+	fmt.Println()
+
+	for {
+		response := prompt("Select devices, e.g. 1,3 or 1-2, \"none\" to abort", "all")
+
+		idx, err := parseSelection(response, len(devices))
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+
+		res := make([]discovery.DiscoveredDevice, 0, len(idx))
+		for _, i := range idx {
+			res = append(res, devices[i])
+		}
+		return res
+	}
+}
+
+// parseSelection parses a comma separated list of 1-based numbers and ranges into sorted indexes
+func parseSelection(s string, n int) ([]int, error) {
+	switch strings.ToLower(s) {
+	case "all", "":
+		s = fmt.Sprintf("1-%d", n)
+	case "none":
+		return nil, nil
+	}
+
+	var res []int
+	for part := range strings.SplitSeq(s, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			to = from
+		}
+
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection: %s", part)
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection: %s", part)
+		}
+
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid selection: %s, choose from 1-%d", part, n)
+		}
+
+		for i := first - 1; i < last; i++ {
+			if !slices.Contains(res, i) {
+				res = append(res, i)
+			}
+		}
+	}
+
+	slices.Sort(res)
+	return res, nil
+}
+
+// suggestRoles observes the paired kWh meters in parallel and suggests their roles, keyed by host
+func suggestRoles(ctx context.Context, paired []PairedDevice) map[string]RoleSuggestion {
+	var meters []PairedDevice
+	for _, d := range paired {
+		if d.Type == device.DeviceTypeKWHMeter {
+			meters = append(meters, d)
+		}
+	}
+
+	res := make(map[string]RoleSuggestion)
+	if len(meters) == 0 {
+		return res
+	}
+
+	fmt.Println()
+	fmt.Printf("Observing the power flow of %d kWh meter(s) for %s...\n", len(meters), DefaultObserveDuration)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, d := range meters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			suggestion, err := SuggestRole(ctx, d, DefaultObserveDuration)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				fmt.Printf("  %s: no role suggestion: %v\n", d.Host, err)
+				return
+			}
+			res[d.Host] = suggestion
+		}()
+	}
+
+	wg.Wait()
+
+	return res
+}
+
+// assignMeters asks for the role and name of each paired device
+// The role defaults to the suggestion if available, otherwise to the usage of its product type
+func assignMeters(paired []PairedDevice, suggestions map[string]RoleSuggestion) []Meter {
+	if len(paired) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Assign a role (grid, pv, charge, battery, aux) and name to each device:")
+
+	res := make([]Meter, 0, len(paired))
+	var names []string
+
+	for i, d := range paired {
+		m := Meter{
+			Usage:  DefaultUsage(d.ProductType, d.Type),
+			Device: d,
+		}
+
+		fmt.Println()
+		fmt.Printf("  %d. %s (%s %s) at %s\n", i+1, d.ProductName, d.ProductType, d.Serial, d.Host)
+
+		// Devices without meter usage, e.g. watermeters, only get a token
+		if m.Usage == "" {
+			fmt.Println("     Not an evcc meter, only a token was created")
+			res = append(res, m)
+			continue
+		}
+
+		if s, ok := suggestions[d.Host]; ok {
+			m.Usage = s.Usage
+			fmt.Printf("     Suggested role: %s (%.0f%% confidence, %s)\n", s.Usage, 100*s.Confidence, s.Reason)
+		}
+
+		for {
+			usage, err := adapter.ParseUsage(strings.ToLower(prompt("     Role", string(m.Usage))))
+			if err == nil {
+				m.Usage = usage
+				break
+			}
+			fmt.Printf("     %v\n", err)
+		}
+
+		for {
+			name := prompt("     Name", UniqueName(string(m.Usage), names))
+			if !meterNamePattern.MatchString(name) {
+				fmt.Println("     invalid name: use letters, digits, - and _ only")
+				continue
+			}
+			if slices.Contains(names, name) {
+				fmt.Printf("     name %s is already used\n", name)
+				continue
+			}
+			m.Name = name
+			break
+		}
+
+		names = append(names, m.Name)
+		res = append(res, m)
+	}
+
+	return res
+}
+
+// statusLines renders one status line per device, updated in place
+type statusLines struct {
+	devices []discovery.DiscoveredDevice
+	failed  int
+}
+
+// newStatusLines prints the initial status line of each device
+func newStatusLines(devices []discovery.DiscoveredDevice) *statusLines {
+	for i, d := range devices {
+		fmt.Printf("[%d] %s: %s\n", i+1, d.Host, "initializing...")
+	}
+
+	return &statusLines{devices: devices}
+}
+
+// update renders a pairing event, events are serialized by Pair
+func (l *statusLines) update(e Event) {
+	idx := slices.IndexFunc(l.devices, func(d discovery.DiscoveredDevice) bool {
+		return d.Host == e.Device.Host
+	})
+	if idx < 0 {
+		return
+	}
+
+	var status string
+	switch e.Type {
+	case EventWaitingForButton:
+		status = fmt.Sprintf("waiting for button press (attempt %d/%d)...", e.Attempt, MaxAttempts)
+	case EventPaired:
+		status = fmt.Sprintf("✓ SUCCESS (%s %s, firmware %s)", e.Paired.ProductType, e.Paired.Serial, e.Paired.FirmwareVersion)
+	case EventFailed:
+		l.failed++
+		status = fmt.Sprintf("✗ FAILED: %v", e.Err)
+	default:
+		return
+	}
+
+	updateStatusLine(idx, e.Device.Host, status, len(l.devices))
+}
+
+func pairDevicesParallel(ctx context.Context, devices []discovery.DiscoveredDevice, name string, opts ...Option) []PairedDevice {
+	lines := newStatusLines(devices)
+
+	// Pairing failures are shown on the status lines
+	paired, err := Pair(ctx, devices, name, lines.update, opts...)
+	fmt.Println()
+
+	for i, w := range pairWarnings(lines.failed, err) {
+		if i == 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Warning: %s\n", w)
+	}
+
+	return paired
+}
+
+// pairWarnings returns the warnings printed after pairing
+// Failed devices are already shown on the status lines, tokens that could not be saved are not.
+func pairWarnings(failed int, err error) []string {
+	if failed == 0 {
+		if err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+
+	res := []string{fmt.Sprintf("%d device(s) failed to pair", failed)}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if errors.Is(err, ErrSaveToken) {
+				res = append(res, err.Error())
+			}
+		}
+	}
+
+	return res
+}
+
+func updateStatusLine(line int, host, status string, totalLines int) {
+	// Move cursor up to the line, clear it, and print new status
+	fmt.Printf("\033[%dA\r\033[K[%d] %s: %s\033[%dB\r",
+		totalLines-line, line+1, host, status, totalLines-line)
+}
+
+func printHomeWizardMultiConfig(meters []Meter, o options) error {
+	fmt.Println()
+	fmt.Println("========================================")
+	fmt.Println("Configuration Complete!")
+	fmt.Println("========================================")
+	fmt.Println()
+
+	cfg := NewConfig(meters, o.site)
+
+	if o.configFile != "" {
+		res, err := MergeConfigFile(o.configFile, cfg)
+		if err != nil {
+			return fmt.Errorf("updating %s: %w", o.configFile, err)
+		}
+
+		fmt.Printf("Updated %s:\n", o.configFile)
+		for _, name := range res.Updated {
+			fmt.Printf("  ~ %s (host and token updated)\n", name)
+		}
+		for _, name := range res.Added {
+			fmt.Printf("  + %s (added)\n", name)
+		}
+		fmt.Println()
+	} else {
+		b, err := cfg.Marshal(o.format)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Add this to your evcc.yaml configuration:")
+		fmt.Println()
+		fmt.Println(string(b))
+	}
+
+	var grids []string
+	for _, m := range cfg.Meters {
+		if m.Usage == adapter.UsageGrid {
+			grids = append(grids, m.Name)
+		}
+	}
+
+	// Print helpful notes
+	if len(meters) > 0 {
+		fmt.Println("# Notes:")
+		if cfg.Site == nil {
+			fmt.Println("# - Make sure to add these devices to your \"site\" as well")
+		}
+		fmt.Println("# - Charge meters are added to a loadpoint, not to the site")
+		if len(grids) > 1 {
+			fmt.Printf("# - Only one of %s can be the site's grid meter\n", strings.Join(grids, ", "))
+		}
+		for _, m := range meters {
+			// Devices without meter usage, e.g. watermeters, can't be used by evcc
+			if m.Usage == "" {
+				fmt.Printf("# - %s (%s) is not an evcc meter, its token is: %s\n", m.Device.Host, m.Device.Type, m.Device.Token)
+			}
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package pairing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/mluiten/evcc-homewizard-v2/discovery"
+)
+
+func TestPairWarnings(t *testing.T) {
+	saveErr := fmt.Errorf("192.168.1.2: %w: %w", ErrSaveToken, errors.New("permission denied"))
+	pairErr := fmt.Errorf("192.168.1.3: %w", errors.New("timeout after 60 attempts"))
+
+	tests := []struct {
+		name   string
+		failed int
+		err    error
+		want   []string
+	}{
+		{"success", 0, nil, nil},
+		{"save failed", 0, errors.Join(saveErr), []string{saveErr.Error()}},
+		{"pairing failed", 1, errors.Join(pairErr), []string{"1 device(s) failed to pair"}},
+		{"both failed", 1, errors.Join(saveErr, pairErr), []string{"1 device(s) failed to pair", saveErr.Error()}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pairWarnings(tc.failed, tc.err); !slices.Equal(got, tc.want) {
+				t.Errorf("warnings = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+		err   bool
+	}{
+		{input: "", want: []int{0, 1, 2, 3}},
+		{input: "all", want: []int{0, 1, 2, 3}},
+		{input: "ALL", want: []int{0, 1, 2, 3}},
+		{input: "none"},
+		{input: "2", want: []int{1}},
+		{input: "3, 1", want: []int{0, 2}},
+		{input: "1-2,4", want: []int{0, 1, 3}},
+		{input: " 2 - 3 ", want: []int{1, 2}},
+		{input: "1,1-2", want: []int{0, 1}},
+		{input: "0", err: true},
+		{input: "5", err: true},
+		{input: "3-2", err: true},
+		{input: "1-5", err: true},
+		{input: "a", err: true},
+		{input: "1,", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseSelection(tc.input, 4)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("parseSelection(%q) = %v, want error", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("parseSelection(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverInteractively(t *testing.T) {
+	want := []discovery.DiscoveredDevice{{Host: "192.168.1.2", Serial: "5c2fafaabbcc", Status: discovery.StatusReady}}
+
+	o := applyOptions(WithDiscovery(func(ctx context.Context, onEvent func(Event)) ([]discovery.DiscoveredDevice, error) {
+		for _, d := range want {
+			onEvent(Event{Type: EventDiscovered, Device: d})
+		}
+		return want, nil
+	}))
+
+	got, err := discoverInteractively(context.Background(), 1, o.discover)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("devices = %+v, %v, want %+v", got, err, want)
+	}
+}